@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// requestIDHeader identifies a request in the audit log. It is generated unless the client gives a valid one.
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+
+	defaultAuditLogLimit = 100
+	maxAuditLogLimit     = 1000
+)
+
+// auditActor is who makes the changes recorded in the audit log, carried in the context of a request.
+type auditActor struct {
+	// UserID is 0 for the changes by the server.
+	UserID    int
+	RequestID string
+}
+
+type auditActorKey struct{}
+
+// withAuditActor returns the context recording the changes made with it as the ones by the actor.
+func withAuditActor(ctx context.Context, actor auditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// auditActorFrom returns the actor in the context, or the zero value for the changes by the server.
+func auditActorFrom(ctx context.Context) auditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(auditActor)
+	return actor
+}
+
+// validRequestID reports whether the request ID given by a client is short and printable enough to be recorded.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// auditMiddleware puts the user and the ID of the request into its context, to record them in the audit log.
+// The request ID is echoed in the X-Request-ID response header, so that a client can look up its changes.
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = rand.Text()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		// the handlers validate the user ID themselves
+		userID, _ := parseOptionalUserID(r)
+		ctx := withAuditActor(r.Context(), auditActor{UserID: userID, RequestID: requestID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// parseAuditLogFilter parses the query parameters of GET /admin/audit-log .
+func parseAuditLogFilter(r *http.Request) (AuditLogFilter, error) {
+	filter := AuditLogFilter{Limit: defaultAuditLogLimit}
+	query := r.URL.Query()
+	var err error
+	if v := query.Get("item_id"); v != "" {
+		filter.ItemID, err = strconv.Atoi(v)
+		if err != nil || filter.ItemID <= 0 {
+			return filter, errors.New("invalid item ID")
+		}
+	}
+	if v := query.Get("since"); v != "" {
+		filter.Since, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("since must be in RFC 3339")
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		filter.Until, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("until must be in RFC 3339")
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		filter.Limit, err = strconv.Atoi(v)
+		if err != nil || filter.Limit <= 0 || filter.Limit > maxAuditLogLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxAuditLogLimit)
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		filter.Offset, err = strconv.Atoi(v)
+		if err != nil || filter.Offset < 0 {
+			return filter, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return filter, nil
+}
+
+// GetAuditLog is a handler to return the audit log of the items, the newest first, for GET /admin/audit-log .
+// It can be filtered by the "item_id" and the time range of "since" (inclusive) and "until" (exclusive) in RFC 3339,
+// and paged by "limit" and "offset".
+func (s *Handlers) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	filter, err := parseAuditLogFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := s.auditLogRepo.LoadAuditLog(r.Context(), filter)
+	if err != nil {
+		slog.Error("failed to load audit log", "error", err)
+		http.Error(w, "failed to load audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"entries": entries}); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		requestID string
+		userID    string
+
+		wantGenerated bool
+		wantUserID    int
+	}{
+		"ok: given request ID": {
+			requestID:  "req-1",
+			userID:     "3",
+			wantUserID: 3,
+		},
+		"ok: generated request ID for an anonymous user": {
+			wantGenerated: true,
+		},
+		"ok: invalid request ID is replaced": {
+			requestID:     "has space",
+			userID:        "3",
+			wantGenerated: true,
+			wantUserID:    3,
+		},
+		"ok: too long request ID is replaced": {
+			requestID:     strings.Repeat("a", maxRequestIDLength+1),
+			wantGenerated: true,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got auditActor
+			handler := auditMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = auditActorFrom(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(requestIDHeader, tt.requestID)
+			}
+			if tt.userID != "" {
+				req.Header.Set(userIDHeader, tt.userID)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if got.UserID != tt.wantUserID {
+				t.Errorf("expected user %d, got %d", tt.wantUserID, got.UserID)
+			}
+			if tt.wantGenerated == (got.RequestID == tt.requestID) || got.RequestID == "" {
+				t.Errorf("unexpected request ID %q for %q", got.RequestID, tt.requestID)
+			}
+			if h := rr.Header().Get(requestIDHeader); h != got.RequestID {
+				t.Errorf("expected the %s header %q, got %q", requestIDHeader, got.RequestID, h)
+			}
+		})
+	}
+}
+
+func TestAuditLogE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := t.Context()
+	itemRepo := newTestItemRepository(t, db)
+	userRepo := NewUserRepository(db)
+	admin := &User{Name: "admin", Email: "admin@example.com", IsAdmin: true}
+	if err := userRepo.Insert(ctx, admin); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+	h := &Handlers{imgDirPath: t.TempDir(), itemRepo: itemRepo, userRepo: userRepo, auditLogRepo: NewAuditLogRepository(db), events: NewEventBus(), adminToken: "secret"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /items", h.AddItem)
+	mux.HandleFunc("PATCH /items/{item_id}", h.UpdateItem)
+	mux.HandleFunc("POST /items/{item_id}/publish", h.PublishItem)
+	mux.HandleFunc("GET /admin/audit-log", h.GetAuditLog)
+	handler := auditMiddleware(mux)
+
+	serve := func(req *http.Request, requestID string) *httptest.ResponseRecorder {
+		req.Header.Set(requestIDHeader, requestID)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		return rr
+	}
+
+	start := time.Now().Add(-time.Second)
+	rr := serve(newAddItemRequest(t, "", map[string]string{"name": "jacket", "category": "fashion"}), "req-create")
+	var item Item
+	if err := json.Unmarshal(rr.Body.Bytes(), &item); err != nil {
+		t.Fatalf("failed to decode item: %v", err)
+	}
+	target := "/items/" + strconv.Itoa(item.ID)
+
+	req := httptest.NewRequest(http.MethodPatch, target, strings.NewReader(url.Values{"name": {"coat"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(userIDHeader, "1")
+	serve(req, "req-update")
+
+	req = httptest.NewRequest(http.MethodPost, target+"/publish", nil)
+	req.Header.Set(userIDHeader, "1")
+	serve(req, "req-publish")
+
+	// a change of another item is not in the log of the item
+	if err := itemRepo.Insert(ctx, &Item{Name: "shirt", Category: "fashion", SellerID: 2}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+
+	loadAuditLog := func(query url.Values) []AuditEntry {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit-log?"+query.Encode(), nil)
+		req.Header.Set(userIDHeader, strconv.Itoa(admin.ID))
+		req.Header.Set(adminTokenHeader, "secret")
+		rr := serve(req, "req-audit")
+		var res struct{ Entries []AuditEntry }
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+			t.Fatalf("failed to decode audit log: %v", err)
+		}
+		return res.Entries
+	}
+
+	entries := loadAuditLog(url.Values{"item_id": {strconv.Itoa(item.ID)}, "since": {start.Format(time.RFC3339)}})
+	type summary struct {
+		Action, RequestID string
+		ActorID           int
+		Before, After     string
+	}
+	var got []summary
+	for _, e := range entries {
+		var before, after *Item
+		if err := json.Unmarshal(e.Before, &before); err != nil {
+			t.Fatalf("failed to decode before: %v", err)
+		}
+		if err := json.Unmarshal(e.After, &after); err != nil {
+			t.Fatalf("failed to decode after: %v", err)
+		}
+		s := summary{Action: e.Action, RequestID: e.RequestID, ActorID: e.ActorID, After: after.Name + "/" + after.Status}
+		if before != nil {
+			s.Before = before.Name + "/" + before.Status
+		}
+		got = append(got, s)
+	}
+	want := []summary{
+		{Action: auditActionPublish, RequestID: "req-publish", ActorID: 1, Before: "coat/draft", After: "coat/published"},
+		{Action: auditActionUpdate, RequestID: "req-update", ActorID: 1, Before: "jacket/draft", After: "coat/draft"},
+		{Action: auditActionCreate, RequestID: "req-create", ActorID: 1, After: "jacket/draft"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected audit log (-want +got):\n%s", diff)
+	}
+
+	if entries := loadAuditLog(url.Values{"limit": {"2"}, "offset": {"1"}}); len(entries) != 2 || entries[0].Action != auditActionPublish {
+		t.Errorf("unexpected page of the audit log: %+v", entries)
+	}
+	if entries := loadAuditLog(url.Values{"until": {start.Format(time.RFC3339)}}); len(entries) != 0 {
+		t.Errorf("expected no entries before the start, got %d", len(entries))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/audit-log", nil)
+	req.Header.Set(userIDHeader, "99")
+	req.Header.Set(adminTokenHeader, "secret")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for a non-admin, got %d", http.StatusForbidden, rr.Code)
+	}
+
+	for _, query := range []string{"UPDATE audit_log SET action = 'none'", "DELETE FROM audit_log"} {
+		if _, err := db.ExecContext(ctx, query); err == nil || !strings.Contains(err.Error(), "append-only") {
+			t.Errorf("expected %q to be rejected, got %v", query, err)
+		}
+	}
+}
+
+func TestAuditLogOfScheduledPublishing(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := t.Context()
+	itemRepo := newTestItemRepository(t, db)
+	item := &Item{Name: "jacket", Category: "fashion", SellerID: 1, Status: itemStatusDraft}
+	if err := itemRepo.Insert(ctx, item); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+	now := time.Now()
+	if err := itemRepo.Publish(ctx, item.ID, 1, now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to schedule item: %v", err)
+	}
+	if _, err := itemRepo.PublishDueItems(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("failed to publish due items: %v", err)
+	}
+
+	entries, err := NewAuditLogRepository(db).LoadAuditLog(ctx, AuditLogFilter{ItemID: item.ID, Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to load audit log: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	// the scheduler publishes the item on behalf of nobody
+	if e := entries[0]; e.Action != auditActionPublish || e.ActorID != 0 || !strings.Contains(string(e.Before), itemStatusScheduled) {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
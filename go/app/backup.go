@@ -0,0 +1,332 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	// backupDBName is the name of the database in a backup archive.
+	backupDBName = "mercari.sqlite3"
+	// backupImagesDir is the directory of the images in a backup archive.
+	backupImagesDir = "images/"
+	// backupFilePrefix and backupFileSuffix make the name of a scheduled backup.
+	backupFilePrefix = "mercari-"
+	backupFileSuffix = ".tar.gz"
+)
+
+var (
+	errBackupNoDB     = errors.New("backup has no database")
+	errBackupNoSchema = errors.New("backup has no schema version")
+	errBackupTooNew   = errors.New("backup has a newer schema than this server")
+)
+
+// copyDB copies the main database of src into dst with the online backup API of SQLite.
+// The copy is a single step in a read transaction on src, so it is a consistent snapshot of src:
+// copying a few pages per step would restart whenever another connection writes to src,
+// and might never finish on a busy server. In the WAL mode, the writers to src go on meanwhile.
+func copyDB(ctx context.Context, dst, src *sql.DB) error {
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(dc any) error {
+		return srcConn.Raw(func(sc any) error {
+			source := sc.(*sqlite3.SQLiteConn)
+			// a read transaction takes its snapshot at the first read, not at BEGIN
+			if _, err := source.Exec("BEGIN", nil); err != nil {
+				return err
+			}
+			defer source.Exec("ROLLBACK", nil)
+			if _, err := source.Exec("SELECT COUNT(*) FROM sqlite_master", nil); err != nil {
+				return err
+			}
+
+			b, err := dc.(*sqlite3.SQLiteConn).Backup("main", source, "main")
+			if err != nil {
+				return err
+			}
+			done, err := b.Step(-1)
+			if err != nil {
+				b.Finish()
+				return err
+			}
+			if !done {
+				b.Finish()
+				return errors.New("backup did not copy every page")
+			}
+			return b.Finish()
+		})
+	})
+}
+
+// writeBackup writes a gzipped tar archive of db, and of the images in imgDir unless it is "", to w.
+func writeBackup(ctx context.Context, w io.Writer, db *sql.DB, imgDir string) error {
+	tmp, err := os.MkdirTemp("", "mercari-backup-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	// take a snapshot of the database first, since the archive needs its size upfront
+	snapshotPath := filepath.Join(tmp, backupDBName)
+	snapshot, err := sql.Open("sqlite3", snapshotPath)
+	if err != nil {
+		return err
+	}
+	err = copyDB(ctx, snapshot, db)
+	snapshot.Close()
+	if err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := addBackupFile(tw, backupDBName, snapshotPath); err != nil {
+		return err
+	}
+	if imgDir != "" {
+		entries, err := os.ReadDir(imgDir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			if err := addBackupFile(tw, backupImagesDir+e.Name(), filepath.Join(imgDir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addBackupFile adds the file at filePath to the archive as name.
+func addBackupFile(tw *tar.Writer, name, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// restoreBackup restores db, and the images into imgDir unless it is "", from the archive written by writeBackup.
+// It rejects the backup with a schema newer than the migrations of this server,
+// migrates the one with an older schema, and returns the schema version of the backup.
+func restoreBackup(ctx context.Context, r io.Reader, db *sql.DB, imgDir string) (string, error) {
+	tmp, err := os.MkdirTemp("", "mercari-restore-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	// extract the archive first, not to restore a part of a broken one
+	images, err := extractBackup(r, tmp)
+	if err != nil {
+		return "", err
+	}
+	snapshotPath := filepath.Join(tmp, backupDBName)
+	if _, err := os.Stat(snapshotPath); err != nil {
+		return "", errBackupNoDB
+	}
+	snapshot, err := sql.Open("sqlite3", snapshotPath)
+	if err != nil {
+		return "", err
+	}
+	defer snapshot.Close()
+
+	version, err := schemaVersion(ctx, snapshot)
+	if err != nil || version == "" {
+		return "", errBackupNoSchema
+	}
+	latest, err := latestMigration()
+	if err != nil {
+		return "", err
+	}
+	if version > latest {
+		return version, fmt.Errorf("%w: %s > %s", errBackupTooNew, version, latest)
+	}
+
+	if err := copyDB(ctx, db, snapshot); err != nil {
+		return version, fmt.Errorf("failed to copy database: %w", err)
+	}
+	if _, err := migrate(ctx, db); err != nil {
+		return version, err
+	}
+	if imgDir != "" {
+		for _, name := range images {
+			if err := copyFile(filepath.Join(imgDir, name), filepath.Join(tmp, backupImagesDir, name)); err != nil {
+				return version, err
+			}
+		}
+	}
+	return version, nil
+}
+
+// extractBackup extracts the database and the images of the archive into dir, and returns the names of the images.
+// It ignores the other entries, including the images in subdirectories, not to write outside dir.
+func extractBackup(r io.Reader, dir string) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	if err := os.Mkdir(filepath.Join(dir, backupImagesDir), 0o755); err != nil {
+		return nil, err
+	}
+
+	var images []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return images, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := hdr.Name
+		if image, ok := strings.CutPrefix(name, backupImagesDir); ok {
+			if image == "" || image != path.Base(image) || strings.HasPrefix(image, ".") {
+				continue
+			}
+			images = append(images, image)
+		} else if name != backupDBName {
+			continue
+		}
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(name)), tr); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// copyFile copies the file at src to dst.
+func copyFile(dst, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeFile(dst, f)
+}
+
+// writeFile writes the content of r to the file at name.
+func writeFile(name string, r io.Reader) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runBackupScheduler writes a backup into dir every interval and keeps the newest keep of them, until ctx is done.
+func runBackupScheduler(ctx context.Context, db *sql.DB, imgDir, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			name, err := scheduledBackup(ctx, db, imgDir, dir, now, keep)
+			if err != nil {
+				slog.Error("failed to back up", "error", err)
+				continue
+			}
+			slog.Info("backed up", "file", name)
+		}
+	}
+}
+
+// scheduledBackup writes a backup named after now into dir, deletes the old ones beyond keep,
+// and returns the path of the backup.
+func scheduledBackup(ctx context.Context, db *sql.DB, imgDir, dir string, now time.Time, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first, not to leave a broken backup among the others
+	f, err := os.CreateTemp(dir, ".backup-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if err := writeBackup(ctx, f, db, imgDir); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, backupFilePrefix+now.UTC().Format("20060102T150405Z")+backupFileSuffix)
+	if err := os.Rename(f.Name(), name); err != nil {
+		return "", err
+	}
+
+	return name, pruneBackups(dir, keep)
+}
+
+// pruneBackups deletes the scheduled backups in dir except the newest keep of them.
+// The other files in dir are left untouched.
+func pruneBackups(dir string, keep int) error {
+	names, err := filepath.Glob(filepath.Join(dir, backupFilePrefix+"*"+backupFileSuffix))
+	if err != nil {
+		return err
+	}
+	// the names contain the time of the backups, so sorting them sorts the backups from the oldest
+	slices.Sort(names)
+	for _, name := range names[:max(0, len(names)-keep)] {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
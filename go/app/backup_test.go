@@ -0,0 +1,182 @@
+package app
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openMigratedDB opens a new database in dir with the migrations applied.
+func openMigratedDB(t *testing.T, dir string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "mercari.sqlite3"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrate(t.Context(), db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func TestBackupRestore(t *testing.T) {
+	t.Parallel()
+
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	src, dst := openMigratedDB(t, srcDir), openMigratedDB(t, dstDir)
+	srcImages, dstImages := filepath.Join(srcDir, "images"), filepath.Join(dstDir, "images")
+	for _, dir := range []string{srcImages, dstImages} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatalf("failed to create image directory: %v", err)
+		}
+	}
+
+	if err := newTestItemRepository(t, src).Insert(t.Context(), &Item{Name: "jacket", Category: "fashion", Image: "a.jpg"}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcImages, "a.jpg"), []byte("jpeg"), 0o644); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeBackup(t.Context(), &buf, src, srcImages); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+	version, err := restoreBackup(t.Context(), &buf, dst, dstImages)
+	if err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	if latest, _ := latestMigration(); version != latest {
+		t.Errorf("expected schema version %q, got %q", latest, version)
+	}
+
+	items, err := newTestItemRepository(t, dst).LoadItems(t.Context(), 0)
+	if err != nil {
+		t.Fatalf("failed to load items: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "jacket" {
+		t.Errorf("expected the backed up item, got %+v", items)
+	}
+	if got, err := os.ReadFile(filepath.Join(dstImages, "a.jpg")); err != nil || string(got) != "jpeg" {
+		t.Errorf("expected the backed up image, got %q (%v)", got, err)
+	}
+}
+
+func TestBackupWhileWriting(t *testing.T) {
+	t.Parallel()
+
+	pools, err := OpenDBPools(t.Context(), filepath.Join(t.TempDir(), "mercari.sqlite3"), DBConfig{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { pools.Close() })
+	if _, err := migrate(t.Context(), pools.Write); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	// enough pages for the writers to commit many times during the backup
+	_, err = pools.Write.Exec(`INSERT INTO categories (name) VALUES ('fashion');
+        WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 20000)
+        INSERT INTO items (name, category_id, image) SELECT printf('item %d %0500d', i, i), 1, '' FROM n`)
+	if err != nil {
+		t.Fatalf("failed to insert items: %v", err)
+	}
+
+	// write to the database through the write pool until the backup finishes, like a busy server
+	stop := make(chan struct{})
+	writes := make(chan int)
+	go func() {
+		n := 0
+		defer func() { writes <- n }()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := pools.Write.Exec("INSERT INTO items (name, category_id, image) VALUES ('jacket', 1, '')"); err != nil {
+				t.Errorf("failed to insert item: %v", err)
+				return
+			}
+			n++
+		}
+	}()
+
+	var buf bytes.Buffer
+	err = writeBackup(t.Context(), &buf, pools.Read, "")
+	close(stop)
+	if n := <-writes; n == 0 {
+		t.Error("expected the writers to go on during the backup")
+	}
+	if err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+
+	dst := openMigratedDB(t, t.TempDir())
+	if _, err := restoreBackup(t.Context(), &buf, dst, ""); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	var count int
+	if err := dst.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil {
+		t.Fatalf("failed to count items: %v", err)
+	}
+	if count < 20000 {
+		t.Errorf("expected at least the items before the backup, got %d", count)
+	}
+}
+
+func TestRestoreNewerSchema(t *testing.T) {
+	t.Parallel()
+
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	src, dst := openMigratedDB(t, srcDir), openMigratedDB(t, dstDir)
+	if _, err := src.Exec("INSERT INTO schema_migrations (version) VALUES ('9999_future')"); err != nil {
+		t.Fatalf("failed to insert version: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeBackup(t.Context(), &buf, src, ""); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+	_, err := restoreBackup(t.Context(), &buf, dst, "")
+	if !errors.Is(err, errBackupTooNew) {
+		t.Errorf("expected errBackupTooNew, got %v", err)
+	}
+}
+
+func TestScheduledBackup(t *testing.T) {
+	t.Parallel()
+
+	db := openMigratedDB(t, t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "other.txt"), nil, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	var names []string
+	for i := range 4 {
+		name, err := scheduledBackup(t.Context(), db, "", dir, start.Add(time.Duration(i)*time.Hour), 2)
+		if err != nil {
+			t.Fatalf("failed to back up: %v", err)
+		}
+		names = append(names, name)
+	}
+
+	for i, name := range names {
+		_, err := os.Stat(name)
+		if exists, want := err == nil, i >= 2; exists != want {
+			t.Errorf("%s: expected exists %v, got %v", filepath.Base(name), want, exists)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.txt")); err != nil {
+		t.Errorf("expected the other files to be kept: %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	bulkFormatCSV    = "csv"
+	bulkFormatNDJSON = "ndjson"
+
+	// bulkBatchSize is the number of items inserted per transaction.
+	bulkBatchSize = 100
+	// maxImportImageSize is the same limit as the upload of POST /items.
+	maxImportImageSize = 32 << 20
+	importImageTimeout = 30 * time.Second
+	maxNDJSONLineSize  = 1 << 20
+)
+
+// BulkRow is a row of a bulk import. Image is a URL, or a file path when importing from the CLI.
+type BulkRow struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Image    string `json:"image"`
+}
+
+// BulkRowResult is the result of importing a row. Row starts from 1, not counting the CSV header.
+type BulkRowResult struct {
+	Row    int    `json:"row"`
+	ItemID int    `json:"item_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkReport is the report of a bulk import.
+type BulkReport struct {
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Rows      []BulkRowResult `json:"rows"`
+}
+
+// itemImporter imports items in bulk from CSV or NDJSON.
+type itemImporter struct {
+	repo       ItemRepository
+	storeImage func(ctx context.Context, image []byte) (string, error)
+	events     *EventBus
+	// client fetches the images, refusing the internal network since the URLs come from the clients.
+	client *http.Client
+	// imageDir is the directory to resolve relative image paths against.
+	// Images can only be given as URLs if it is empty, so that API clients cannot read files of the server.
+	imageDir  string
+	batchSize int
+	// moderation puts the flagged items in review. Nil passes every item.
+	moderation *itemModeration
+}
+
+func newItemImporter(repo ItemRepository, storeImage func(context.Context, []byte) (string, error), events *EventBus) *itemImporter {
+	return &itemImporter{
+		repo:       repo,
+		storeImage: storeImage,
+		events:     events,
+		client:     newPublicHTTPClient(importImageTimeout),
+		batchSize:  bulkBatchSize,
+	}
+}
+
+// Import validates every row and inserts the valid ones in batches, as items of the seller with the status.
+// It returns an error only if the input cannot be read any further; errors of rows are in the report.
+func (im *itemImporter) Import(ctx context.Context, r io.Reader, format string, sellerID int, status string) (*BulkReport, error) {
+	report := &BulkReport{Rows: []BulkRowResult{}}
+	var batch []*Item
+	var batchRows []int
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := im.repo.InsertItems(ctx, batch)
+		for i, item := range batch {
+			res := &report.Rows[batchRows[i]]
+			if err != nil {
+				res.Error = fmt.Sprintf("failed to insert item: %v", err)
+				report.Failed++
+				continue
+			}
+			res.ItemID = item.ID
+			report.Succeeded++
+			if item.Status == itemStatusPublished {
+				im.events.Publish(eventItemCreated, item)
+			}
+		}
+		batch, batchRows = nil, nil
+	}
+
+	err := decodeBulkRows(r, format, func(n int, row BulkRow, rowErr error) {
+		report.Rows = append(report.Rows, BulkRowResult{Row: n})
+		item, err := im.buildItem(ctx, row, rowErr)
+		if err != nil {
+			report.Rows[len(report.Rows)-1].Error = err.Error()
+			report.Failed++
+			return
+		}
+		item.SellerID = sellerID
+		item.Status = status
+		batch = append(batch, item)
+		batchRows = append(batchRows, len(report.Rows)-1)
+		im.moderation.check(item)
+		if len(batch) >= im.batchSize {
+			flush()
+		}
+	})
+	flush()
+	return report, err
+}
+
+// buildItem validates a row and stores its image.
+func (im *itemImporter) buildItem(ctx context.Context, row BulkRow, rowErr error) (*Item, error) {
+	if rowErr != nil {
+		return nil, rowErr
+	}
+	if row.Name == "" || row.Category == "" {
+		return nil, errors.New("name and category are required")
+	}
+	image, err := im.loadImage(ctx, row.Image)
+	if err != nil {
+		return nil, err
+	}
+	fileName, err := im.storeImage(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+	return &Item{Name: row.Name, Category: row.Category, Image: fileName}, nil
+}
+
+// loadImage fetches the image at a URL on the public internet or, if allowed, reads it from a file.
+func (im *itemImporter) loadImage(ctx context.Context, location string) ([]byte, error) {
+	if location == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(location)
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := im.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch image: %w", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch image: unexpected status code %d", res.StatusCode)
+		}
+		// give up before downloading an image known to be too large
+		if res.ContentLength > maxImportImageSize {
+			return nil, errors.New("image is too large")
+		}
+		return readImage(res.Body)
+	}
+
+	if im.imageDir == "" {
+		return nil, errors.New("image must be an http or https URL")
+	}
+	path := location
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(im.imageDir, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer f.Close()
+	return readImage(f)
+}
+
+func readImage(r io.Reader) ([]byte, error) {
+	image, err := io.ReadAll(io.LimitReader(r, maxImportImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(image) > maxImportImageSize {
+		return nil, errors.New("image is too large")
+	}
+	return image, nil
+}
+
+// decodeBulkRows calls fn for every row of r, numbered from 1.
+// A row which cannot be decoded is passed with its error, and decoding goes on with the next row.
+// It returns an error if the input cannot be read any further.
+func decodeBulkRows(r io.Reader, format string, fn func(n int, row BulkRow, err error)) error {
+	switch format {
+	case bulkFormatCSV:
+		return decodeBulkCSV(r, fn)
+	case bulkFormatNDJSON:
+		return decodeBulkNDJSON(r, fn)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// decodeBulkCSV decodes CSV with a header row of name, category and optionally image in any order.
+func decodeBulkCSV(r io.Reader, fn func(int, BulkRow, error)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	column := func(name string) int {
+		return slices.IndexFunc(header, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), name) })
+	}
+	nameCol, categoryCol, imageCol := column("name"), column("category"), column("image")
+	if nameCol < 0 || categoryCol < 0 {
+		return errors.New("CSV header must have name and category columns")
+	}
+
+	for n := 1; ; n++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			fn(n, BulkRow{}, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(record) != len(header) {
+			fn(n, BulkRow{}, fmt.Errorf("expected %d fields, got %d", len(header), len(record)))
+			continue
+		}
+
+		row := BulkRow{Name: record[nameCol], Category: record[categoryCol]}
+		if imageCol >= 0 {
+			row.Image = record[imageCol]
+		}
+		fn(n, row, nil)
+	}
+}
+
+// decodeBulkNDJSON decodes newline delimited JSON objects. Rows are numbered by line, and blank lines are skipped.
+func decodeBulkNDJSON(r io.Reader, fn func(int, BulkRow, error)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxNDJSONLineSize)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var row BulkRow
+		if err := json.Unmarshal(line, &row); err != nil {
+			fn(n, BulkRow{}, err)
+			continue
+		}
+		fn(n, row, nil)
+	}
+	return sc.Err()
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestItemImporterImport(t *testing.T) {
+	t.Parallel()
+
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/jacket.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("jacket"))
+	}))
+	t.Cleanup(imageServer.Close)
+
+	// insertOK assigns IDs to the items in the order they are inserted
+	insertOK := func(m *MockItemRepository, batches ...int) {
+		nextID := 1
+		for _, size := range batches {
+			m.EXPECT().InsertItems(gomock.Any(), gomock.Len(size)).DoAndReturn(func(_ any, items []*Item) error {
+				for _, item := range items {
+					item.ID = nextID
+					nextID++
+				}
+				return nil
+			})
+		}
+	}
+
+	cases := map[string]struct {
+		format   string
+		input    string
+		injector func(m *MockItemRepository)
+		wants    []BulkRowResult
+	}{
+		"ok: csv in batches": {
+			format: bulkFormatCSV,
+			input: "name,category,image\n" +
+				"jacket,fashion," + imageServer.URL + "/jacket.jpg\n" +
+				"shoes,fashion,\n" +
+				"hat,fashion,\n",
+			injector: func(m *MockItemRepository) { insertOK(m, 2, 1) },
+			wants: []BulkRowResult{
+				{Row: 1, ItemID: 1},
+				{Row: 2, ItemID: 2},
+				{Row: 3, ItemID: 3},
+			},
+		},
+		"ng: invalid csv rows": {
+			format: bulkFormatCSV,
+			input: "category,name\n" +
+				"fashion,jacket\n" +
+				"fashion,\n" +
+				"fashion\n" +
+				"fashion,shoes\n",
+			injector: func(m *MockItemRepository) { insertOK(m, 2) },
+			wants: []BulkRowResult{
+				{Row: 1, ItemID: 1},
+				{Row: 2, Error: "name and category are required"},
+				{Row: 3, Error: "expected 2 fields, got 1"},
+				{Row: 4, ItemID: 2},
+			},
+		},
+		"ng: invalid images": {
+			format: bulkFormatNDJSON,
+			input: `{"name":"jacket","category":"fashion","image":"` + imageServer.URL + `/missing.jpg"}` + "\n" +
+				`{"name":"jacket","category":"fashion","image":"/etc/passwd"}` + "\n",
+			injector: func(m *MockItemRepository) {},
+			wants: []BulkRowResult{
+				{Row: 1, Error: "failed to fetch image: unexpected status code 404"},
+				{Row: 2, Error: "image must be an http or https URL"},
+			},
+		},
+		"ng: failed batch": {
+			format: bulkFormatNDJSON,
+			input: `{"name":"jacket","category":"fashion"}` + "\n" +
+				"\n" +
+				`{"name":` + "\n" +
+				`{"name":"shoes","category":"fashion"}` + "\n",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().InsertItems(gomock.Any(), gomock.Len(2)).Return(errors.New("database is locked"))
+			},
+			wants: []BulkRowResult{
+				{Row: 1, Error: "failed to insert item: database is locked"},
+				{Row: 3, Error: "unexpected end of JSON input"},
+				{Row: 4, Error: "failed to insert item: database is locked"},
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+
+			storeImage := func(_ context.Context, image []byte) (string, error) { return string(image) + ".jpg", nil }
+			importer := newItemImporter(mockIR, storeImage, nil)
+			// the image server listens on the loopback, which the client of the importer refuses
+			importer.client = imageServer.Client()
+			importer.batchSize = 2
+
+			report, err := importer.Import(t.Context(), strings.NewReader(tt.input), tt.format, 1, itemStatusDraft)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.wants, report.Rows); diff != "" {
+				t.Errorf("unexpected report (-want +got):\n%s", diff)
+			}
+			failed := 0
+			for _, row := range tt.wants {
+				if row.Error != "" {
+					failed++
+				}
+			}
+			if report.Failed != failed || report.Succeeded != len(tt.wants)-failed {
+				t.Errorf("unexpected counts: succeeded=%d, failed=%d", report.Succeeded, report.Failed)
+			}
+		})
+	}
+}
+
+func TestItemImporterRefusesInternalHosts(t *testing.T) {
+	t.Parallel()
+
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the internal network: %s", r.URL)
+	}))
+	t.Cleanup(imageServer.Close)
+
+	ctrl := gomock.NewController(t)
+	importer := newItemImporter(NewMockItemRepository(ctrl), nil, nil)
+	input := `{"name":"jacket","category":"fashion","image":"` + imageServer.URL + `/jacket.jpg"}` + "\n" +
+		`{"name":"jacket","category":"fashion","image":"http://169.254.169.254/latest/meta-data"}` + "\n"
+
+	report, err := importer.Import(t.Context(), strings.NewReader(input), bulkFormatNDJSON, 1, itemStatusDraft)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Failed != 2 {
+		t.Fatalf("expected every row to fail, got %+v", report)
+	}
+	for _, row := range report.Rows {
+		if !strings.Contains(row.Error, errInternalAddress.Error()) {
+			t.Errorf("row %d: expected the image to be refused, got %q", row.Row, row.Error)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ItemCacheConfig is the configuration of the cache of the items in front of the database.
+type ItemCacheConfig struct {
+	// Size is the maximum number of the cached results. The cache is disabled if it or TTL is 0, which is the default.
+	Size int
+	// TTL is how long a result is cached.
+	// It bounds how stale the like and comment counts can be, since they change without going through ItemRepository.
+	TTL time.Duration
+}
+
+// cachedItemRepository is an ItemRepository caching the results of LoadItems and LoadItem of repo.
+// Every write through it invalidates the whole cache, since an item appears in the results of many viewers.
+type cachedItemRepository struct {
+	ItemRepository
+	cache *lruCache
+	// group collapses the concurrent misses of the same key into a single load.
+	group singleflight.Group
+}
+
+// NewCachedItemRepository returns repo with a read-through cache of GET /items and GET /items/{item_id} in front of it.
+// It returns repo itself if the cache is disabled.
+func NewCachedItemRepository(repo ItemRepository, cfg ItemCacheConfig) ItemRepository {
+	if cfg.Size <= 0 || cfg.TTL <= 0 {
+		return repo
+	}
+	return &cachedItemRepository{ItemRepository: repo, cache: newLRUCache(cfg.Size, cfg.TTL)}
+}
+
+func (c *cachedItemRepository) LoadItems(ctx context.Context, viewerID int) ([]*Item, error) {
+	v, err := c.load(ctx, fmt.Sprintf("items:%d", viewerID), func(ctx context.Context) (any, error) {
+		return c.ItemRepository.LoadItems(ctx, viewerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyItems(v.([]*Item)), nil
+}
+
+func (c *cachedItemRepository) LoadItem(ctx context.Context, itemID, viewerID int) (*Item, error) {
+	v, err := c.load(ctx, fmt.Sprintf("item:%d:%d", itemID, viewerID), func(ctx context.Context) (any, error) {
+		return c.ItemRepository.LoadItem(ctx, itemID, viewerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	copied := *v.(*Item)
+	return &copied, nil
+}
+
+// load returns the cached value of key, or loads and caches it on a miss.
+// The errors are not cached, so that errItemNotFound does not hide an item published right after.
+func (c *cachedItemRepository) load(ctx context.Context, key string, fn func(context.Context) (any, error)) (any, error) {
+	if v, ok := c.cache.Get(key); ok {
+		return v, nil
+	}
+	// a write during the load may make the result stale, so the generation tells whether to cache it,
+	// and keeps the callers after the write from sharing the load started before it
+	gen := c.cache.Generation()
+	v, err, _ := c.group.Do(fmt.Sprintf("%s@%d", key, gen), func() (any, error) {
+		// the load is shared by the callers, so it should not be canceled by the first one
+		v, err := fn(context.WithoutCancel(ctx))
+		if err == nil {
+			c.cache.Add(key, v, gen)
+		}
+		return v, err
+	})
+	return v, err
+}
+
+func (c *cachedItemRepository) Insert(ctx context.Context, item *Item) error {
+	defer c.cache.Purge()
+	return c.ItemRepository.Insert(ctx, item)
+}
+
+func (c *cachedItemRepository) InsertItems(ctx context.Context, items []*Item) error {
+	defer c.cache.Purge()
+	return c.ItemRepository.InsertItems(ctx, items)
+}
+
+func (c *cachedItemRepository) Update(ctx context.Context, item *Item) error {
+	defer c.cache.Purge()
+	return c.ItemRepository.Update(ctx, item)
+}
+
+func (c *cachedItemRepository) Publish(ctx context.Context, itemID, sellerID int, publishAt time.Time) error {
+	defer c.cache.Purge()
+	return c.ItemRepository.Publish(ctx, itemID, sellerID, publishAt)
+}
+
+func (c *cachedItemRepository) PublishDueItems(ctx context.Context, now time.Time) ([]int, error) {
+	ids, err := c.ItemRepository.PublishDueItems(ctx, now)
+	if len(ids) > 0 {
+		c.cache.Purge()
+	}
+	return ids, err
+}
+
+// cachedModerationRepository is a ModerationRepository purging the cache of the items after changing their status,
+// since the moderation writes the items without going through ItemRepository.
+type cachedModerationRepository struct {
+	ModerationRepository
+	cache *lruCache
+}
+
+// NewCachedModerationRepository returns repo purging the cache of items after every decision,
+// or repo itself if items has no cache.
+func NewCachedModerationRepository(repo ModerationRepository, items ItemRepository) ModerationRepository {
+	cached, ok := items.(*cachedItemRepository)
+	if !ok {
+		return repo
+	}
+	return &cachedModerationRepository{ModerationRepository: repo, cache: cached.cache}
+}
+
+func (c *cachedModerationRepository) Flag(ctx context.Context, itemID int, reasons []string) error {
+	defer c.cache.Purge()
+	return c.ModerationRepository.Flag(ctx, itemID, reasons)
+}
+
+func (c *cachedModerationRepository) Approve(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error) {
+	defer c.cache.Purge()
+	return c.ModerationRepository.Approve(ctx, itemID, adminID, note)
+}
+
+func (c *cachedModerationRepository) Reject(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error) {
+	defer c.cache.Purge()
+	return c.ModerationRepository.Reject(ctx, itemID, adminID, note)
+}
+
+// copyItems copies the items, not to let the callers modify the cached ones.
+func copyItems(items []*Item) []*Item {
+	if items == nil {
+		return nil
+	}
+	copied := make([]*Item, len(items))
+	for i, item := range items {
+		c := *item
+		copied[i] = &c
+	}
+	return copied
+}
+
+// lruCache is a cache evicting the least recently used entry when it is full, and the entries older than ttl.
+type lruCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order has the entries from the most recently used one.
+	order *list.List
+	// generation counts the purges.
+	generation uint64
+}
+
+type lruEntry struct {
+	key       string
+	value     any
+	expiresAt time.Time
+}
+
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	return &lruCache{size: size, ttl: ttl, now: time.Now, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Get returns the value of key unless it is missing or expired.
+func (c *lruCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true
+}
+
+// Add caches the value of key loaded at the generation, unless the cache has been purged since then.
+func (c *lruCache) Add(key string, value any, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	entry := &lruEntry{key: key, value: value, expiresAt: c.now().Add(c.ttl)}
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Generation returns the current generation, to pass to Add after loading a value.
+func (c *lruCache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Purge removes all the entries, and makes the values loaded before not to be cached.
+func (c *lruCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.order.Init()
+	c.generation++
+}
+
+// Len returns the number of the entries, including the expired ones not removed yet.
+func (c *lruCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
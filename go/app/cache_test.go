@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCachedItemRepository(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	items := []*Item{{ID: 1, Name: "jacket", Category: "fashion", Status: itemStatusPublished}}
+
+	t.Run("hit", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockIR := NewMockItemRepository(ctrl)
+		mockIR.EXPECT().LoadItems(gomock.Any(), 0).Return(items, nil).Times(1)
+		repo := NewCachedItemRepository(mockIR, ItemCacheConfig{Size: 10, TTL: time.Minute})
+
+		got, err := repo.LoadItems(ctx, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// the callers cannot modify the cached items
+		got[0].Name = "modified"
+		got, err = repo.LoadItems(ctx, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(items, got); diff != "" {
+			t.Errorf("unexpected items (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("keyed by viewer", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockIR := NewMockItemRepository(ctrl)
+		mockIR.EXPECT().LoadItem(gomock.Any(), 1, 0).Return(items[0], nil).Times(1)
+		mockIR.EXPECT().LoadItem(gomock.Any(), 1, 10).Return(items[0], nil).Times(1)
+		repo := NewCachedItemRepository(mockIR, ItemCacheConfig{Size: 10, TTL: time.Minute})
+
+		for _, viewerID := range []int{0, 10, 0, 10} {
+			if _, err := repo.LoadItem(ctx, 1, viewerID); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	t.Run("invalidated by writes", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockIR := NewMockItemRepository(ctrl)
+		mockIR.EXPECT().LoadItems(gomock.Any(), 0).Return(items, nil).Times(3)
+		mockIR.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil)
+		mockIR.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+		repo := NewCachedItemRepository(mockIR, ItemCacheConfig{Size: 10, TTL: time.Minute})
+
+		for _, write := range []func() error{
+			func() error { return repo.Insert(ctx, &Item{}) },
+			func() error { return repo.Update(ctx, &Item{}) },
+		} {
+			if _, err := repo.LoadItems(ctx, 0); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := write(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if _, err := repo.LoadItems(ctx, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockIR := NewMockItemRepository(ctrl)
+		mockIR.EXPECT().LoadItem(gomock.Any(), 1, 0).Return(nil, errItemNotFound).Times(2)
+		repo := NewCachedItemRepository(mockIR, ItemCacheConfig{Size: 10, TTL: time.Minute})
+
+		for range 2 {
+			if _, err := repo.LoadItem(ctx, 1, 0); !errors.Is(err, errItemNotFound) {
+				t.Fatalf("expected errItemNotFound, got %v", err)
+			}
+		}
+	})
+
+	t.Run("concurrent misses", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockIR := NewMockItemRepository(ctrl)
+		mockIR.EXPECT().LoadItems(gomock.Any(), 0).DoAndReturn(func(context.Context, int) ([]*Item, error) {
+			time.Sleep(50 * time.Millisecond)
+			return items, nil
+		}).Times(1)
+		repo := NewCachedItemRepository(mockIR, ItemCacheConfig{Size: 10, TTL: time.Minute})
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := repo.LoadItems(ctx, 0); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockIR := NewMockItemRepository(ctrl)
+		if repo := NewCachedItemRepository(mockIR, ItemCacheConfig{}); repo != mockIR {
+			t.Errorf("expected the repository itself, got %T", repo)
+		}
+	})
+}
+
+func TestCachedModerationRepository(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	mockMR := NewMockModerationRepository(ctrl)
+	items := NewCachedItemRepository(mockIR, ItemCacheConfig{Size: 10, TTL: time.Minute})
+	repo := NewCachedModerationRepository(mockMR, items)
+
+	mockIR.EXPECT().LoadItem(gomock.Any(), 1, 0).Return(&Item{ID: 1, Status: itemStatusPendingReview}, nil)
+	mockIR.EXPECT().LoadItem(gomock.Any(), 1, 0).Return(&Item{ID: 1, Status: itemStatusRejected}, nil)
+	mockMR.EXPECT().Reject(gomock.Any(), 1, 2, "").Return(&ModerationEvent{}, nil)
+
+	for _, want := range []string{itemStatusPendingReview, itemStatusPendingReview} {
+		if item, err := items.LoadItem(ctx, 1, 0); err != nil || item.Status != want {
+			t.Fatalf("expected status %s, got %+v (%v)", want, item, err)
+		}
+	}
+	if _, err := repo.Reject(ctx, 1, 2, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the decision is visible right away, not after the TTL
+	if item, err := items.LoadItem(ctx, 1, 0); err != nil || item.Status != itemStatusRejected {
+		t.Errorf("expected status %s, got %+v (%v)", itemStatusRejected, item, err)
+	}
+
+	if repo := NewCachedModerationRepository(mockMR, mockIR); repo != mockMR {
+		t.Errorf("expected the repository itself without a cache, got %T", repo)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	c := newLRUCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", 1, c.Generation())
+	c.Add("b", 2, c.Generation())
+	c.Get("a")
+	// b is the least recently used
+	c.Add("c", 3, c.Generation())
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("expected a to be kept, got %v", v)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to expire")
+	}
+
+	gen := c.Generation()
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("expected the cache to be empty, got %d entries", c.Len())
+	}
+	c.Add("d", 4, gen)
+	if _, ok := c.Get("d"); ok {
+		t.Error("expected the value loaded before the purge not to be cached")
+	}
+}
@@ -0,0 +1,481 @@
+package app
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// command is a subcommand of the CLI, whose run returns the exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(s Server, args []string) int
+}
+
+var commands = []command{
+	{"serve", "start the HTTP server (default)", func(s Server, _ []string) int { return s.Run() }},
+	{"migrate", "apply the database migrations", Server.Migrate},
+	{"import", "import items in bulk from a CSV or NDJSON file", Server.Import},
+	{"export", "export the published items as CSV, TSV or NDJSON", Server.Export},
+	{"gc-images", "delete the images not referenced by any item", Server.GCImages},
+	{"create-user", "create a user", Server.CreateUser},
+	{"reindex-search", "rebuild the indexes and statistics used by search", Server.ReindexSearch},
+	{"backup", "back up the database and the images while the server is running", Server.Backup},
+	{"restore", "restore the database and the images from a backup", Server.Restore},
+}
+
+// Main is a method to run the subcommand given by args, for the entry point of the application.
+// The global flags before the subcommand override the configuration of the server for every subcommand.
+// It returns the exit code of the subcommand.
+func (s Server) Main(args []string) int {
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	fs.StringVar(&s.Port, "port", s.Port, "port number to listen on")
+	fs.StringVar(&s.GRPCPort, "grpc-port", s.GRPCPort, "port number to serve the gRPC API on (default: not served)")
+	fs.StringVar(&s.ImageDirPath, "images", s.ImageDirPath, "path to the directory storing images")
+	fs.StringVar(&s.DBPath, "db", cmp.Or(s.DBPath, defaultDBPath), "path to the SQLite database file")
+	fs.StringVar(&s.DB.JournalMode, "db-journal-mode", s.DB.JournalMode, "journal mode of the database (default WAL)")
+	fs.DurationVar(&s.DB.BusyTimeout, "db-busy-timeout", s.DB.BusyTimeout, "how long to wait for a lock of the database (default 5s)")
+	fs.IntVar(&s.DB.MaxReadConns, "db-max-read-conns", s.DB.MaxReadConns, "maximum number of connections to read the database (default the number of CPUs)")
+	fs.Func("db-pragma", "pragma to apply on every connection, such as \"cache_size = -20000\" (repeatable)", func(v string) error {
+		s.DB.Pragmas = append(s.DB.Pragmas, v)
+		return nil
+	})
+	fs.IntVar(&s.ItemCache.Size, "item-cache-size", s.ItemCache.Size, "maximum number of the cached item results, like 1024 (default 0, not cached)")
+	fs.DurationVar(&s.ItemCache.TTL, "item-cache-ttl", cmp.Or(s.ItemCache.TTL, 10*time.Second), "how long to cache the item results when -item-cache-size is set")
+	fs.StringVar(&s.TraceExporter, "trace-exporter", s.TraceExporter, "where to export the traces: none, stdout or otlp (default $TRACE_EXPORTER or none)")
+	fs.StringVar(&s.TraceEndpoint, "trace-endpoint", s.TraceEndpoint, "endpoint of OTLP like localhost:4318 (default $OTEL_EXPORTER_OTLP_ENDPOINT)")
+	fs.StringVar(&s.BackupDir, "backup-dir", s.BackupDir, "directory to write the scheduled backups into")
+	fs.DurationVar(&s.BackupInterval, "backup-interval", s.BackupInterval, "interval of the scheduled backups (0 to disable them)")
+	fs.IntVar(&s.BackupKeep, "backup-keep", cmp.Or(s.BackupKeep, 7), "number of the scheduled backups to keep")
+	fs.DurationVar(&s.IdempotencyKeyTTL, "idempotency-key-ttl", cmp.Or(s.IdempotencyKeyTTL, defaultIdempotencyKeyTTL), "how long to replay the responses of POST /items for the retries with the same Idempotency-Key")
+	fs.Func("moderation-config", "JSON file of the rules to put the items in review, with banned_words, review_categories and blocked_image_hashes", func(v string) error {
+		cfg, err := loadModerationConfig(v)
+		s.Moderation = cfg
+		return err
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: api [flags] [command] [command flags]")
+		fmt.Fprintln(fs.Output(), "\ncommands:")
+		for _, c := range commands {
+			fmt.Fprintf(fs.Output(), "  %-16s%s\n", c.name, c.summary)
+		}
+		fmt.Fprintln(fs.Output(), "\nflags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	name := cmp.Or(fs.Arg(0), "serve")
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == name })
+	if i < 0 {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", name)
+		fs.Usage()
+		return 1
+	}
+	return commands[i].run(s, fs.Args()[min(1, fs.NArg()):])
+}
+
+// Migrate is a method to apply the database migrations, for the `migrate` subcommand.
+func (s Server) Migrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	db, err := s.openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	applied, err := migrate(ctx, db)
+	for _, version := range applied {
+		fmt.Printf("applied %s\n", version)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	version, err := schemaVersion(ctx, db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get schema version: %v\n", err)
+		return 1
+	}
+	fmt.Printf("schema version: %s\n", version)
+	return 0
+}
+
+// Import is a method to import items in bulk from a CSV or NDJSON file, for the `import` subcommand.
+// It prints the report as JSON, and returns 0 if every row was imported, and 1 otherwise.
+func (s Server) Import(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "format of the file: csv or ndjson (default: guessed from the extension)")
+	sellerID := fs.Int("seller", 0, "ID of the user selling the items (required)")
+	publish := fs.Bool("publish", false, "publish the items instead of creating drafts")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: api import [flags] FILE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 || *sellerID <= 0 {
+		fs.Usage()
+		return 1
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(path), ".")
+		if *format == "jsonl" {
+			*format = bulkFormatNDJSON
+		}
+	}
+	status := itemStatusDraft
+	if *publish {
+		status = itemStatusPublished
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open file: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	db, err := s.openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	itemRepo, err := NewItemRepository(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer itemRepo.Close()
+
+	h := &Handlers{imgDirPath: s.ImageDirPath, imageRepo: NewImageRepository(db)}
+	importer := newItemImporter(itemRepo, h.storeImage, nil)
+	// the imported items are moderated like the ones of POST /items:bulk
+	importer.moderation = newItemModeration(s.Moderation.Rules(), NewModerationRepository(db))
+	// image paths in the file are relative to the file
+	importer.imageDir = filepath.Dir(path)
+
+	report, err := importer.Import(context.Background(), f, *format, *sellerID, status)
+	if report != nil {
+		printJSON(os.Stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to import: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "imported %d items, %d failed\n", report.Succeeded, report.Failed)
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Export is a method to export the published items to a file or the standard output, for the `export` subcommand.
+// It returns 0 if the items were exported successfully, and 1 otherwise.
+func (s Server) Export(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", exportFormatCSV, "format of the export: csv, tsv or ndjson")
+	output := fs.String("o", "", "file to write the export to (default: the standard output)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: api export [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 1
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create file: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	exporter, err := newItemExporter(bw, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	db, err := s.openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+	itemRepo, err := NewItemRepository(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer itemRepo.Close()
+
+	err = itemRepo.ForEachItem(context.Background(), 0, exporter.Write)
+	if err == nil {
+		err = exporter.Flush()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to export: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// GCImages is a method to delete the images not referenced by any item, for the `gc-images` subcommand.
+// Only the files named like a stored image are deleted, together with their records, so that the other files
+// in the directory such as .gitignore are kept. The default image and the recent images are kept as well,
+// since an upload stores its image before its item.
+func (s Server) GCImages(args []string) int {
+	fs := flag.NewFlagSet("gc-images", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only print the images to delete")
+	minAge := fs.Duration("min-age", time.Hour, "keep the images modified more recently than this")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	db, err := s.openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+	itemRepo, err := NewItemRepository(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer itemRepo.Close()
+
+	names, err := itemRepo.LoadImageNames(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load image names: %v\n", err)
+		return 1
+	}
+	referenced := map[string]bool{"default.jpg": true}
+	for _, name := range names {
+		referenced[name] = true
+	}
+
+	entries, err := os.ReadDir(s.ImageDirPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read image directory: %v\n", err)
+		return 1
+	}
+	deleted := 0
+	imageRepo := NewImageRepository(db)
+	for _, e := range entries {
+		if e.IsDir() || referenced[e.Name()] || !isStoredImageName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < *minAge {
+			continue
+		}
+		fmt.Println(e.Name())
+		if *dryRun {
+			continue
+		}
+		if err := imageRepo.Delete(context.Background(), e.Name()); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to delete image record: %v\n", err)
+			return 1
+		}
+		if err := os.Remove(filepath.Join(s.ImageDirPath, e.Name())); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to delete image: %v\n", err)
+			return 1
+		}
+		deleted++
+	}
+	fmt.Fprintf(os.Stderr, "deleted %d images\n", deleted)
+	return 0
+}
+
+// CreateUser is a method to create a user, for the `create-user` subcommand.
+// It prints the user as JSON, whose ID is the one to send in the X-User-ID header.
+func (s Server) CreateUser(args []string) int {
+	fs := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the user (required)")
+	email := fs.String("email", "", "email address of the user (required)")
+	admin := fs.Bool("admin", false, "allow the user to review the items flagged by the moderation")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if *name == "" || !strings.Contains(*email, "@") {
+		fs.Usage()
+		return 1
+	}
+
+	db, err := s.openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	user := &User{Name: *name, Email: *email, IsAdmin: *admin}
+	if err := NewUserRepository(db).Insert(context.Background(), user); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create user: %v\n", err)
+		return 1
+	}
+	return printJSON(os.Stdout, user)
+}
+
+// ReindexSearch is a method to rebuild the indexes and the statistics of the query planner
+// used by search, for the `reindex-search` subcommand.
+func (s Server) ReindexSearch(args []string) int {
+	fs := flag.NewFlagSet("reindex-search", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	db, err := s.openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{"REINDEX items", "REINDEX categories", "ANALYZE items", "ANALYZE categories"} {
+		if _, err := db.Exec(stmt); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to %s: %v\n", stmt, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// Backup is a method to write a backup of the database and the images, for the `backup` subcommand.
+// It is safe to run while the server is running.
+func (s Server) Backup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	skipImages := fs.Bool("skip-images", false, "back up only the database")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: api backup [flags] FILE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	db, err := s.openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	f, err := os.Create(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create backup: %v\n", err)
+		return 1
+	}
+	imgDir := s.ImageDirPath
+	if *skipImages {
+		imgDir = ""
+	}
+	err = writeBackup(context.Background(), f, db, imgDir)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(fs.Arg(0))
+		fmt.Fprintf(os.Stderr, "failed to back up: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// Restore is a method to restore the database and the images from a backup, for the `restore` subcommand.
+// Stop the server before restoring, since it does not expect the data to change under it.
+func (s Server) Restore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	skipImages := fs.Bool("skip-images", false, "restore only the database")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: api restore [flags] FILE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open backup: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	db, err := s.openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	imgDir := s.ImageDirPath
+	if *skipImages {
+		imgDir = ""
+	}
+	version, err := restoreBackup(context.Background(), f, db, imgDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to restore: %v\n", err)
+		return 1
+	}
+	fmt.Printf("restored schema version: %s\n", version)
+	return 0
+}
+
+// printJSON prints v as indented JSON and returns the exit code.
+func printJSON(w io.Writer, v any) int {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DBConfig is the configuration of the connections to the SQLite database.
+// The zero value is the recommended configuration for the server.
+type DBConfig struct {
+	// JournalMode is the journal mode of the database. It defaults to WAL,
+	// which lets the readers run while a writer writes.
+	JournalMode string
+	// BusyTimeout is how long a connection waits for a lock before failing with SQLITE_BUSY. It defaults to 5s.
+	BusyTimeout time.Duration
+	// Synchronous is how often SQLite syncs the file. It defaults to NORMAL, which is durable enough in the WAL mode.
+	Synchronous string
+	// DisableForeignKeys disables the foreign key constraints, which are enforced by default.
+	DisableForeignKeys bool
+	// Pragmas are the other pragmas applied on every connection, such as "cache_size = -20000".
+	Pragmas []string
+	// MaxReadConns is the maximum number of connections to read. It defaults to the number of CPUs, and at least 4.
+	MaxReadConns int
+}
+
+// pragmas returns the pragmas to apply on every connection.
+// The journal mode is left out, since it is a setting of the database and not of a connection.
+func (c DBConfig) pragmas() []string {
+	foreignKeys := "ON"
+	if c.DisableForeignKeys {
+		foreignKeys = "OFF"
+	}
+	pragmas := []string{
+		fmt.Sprintf("busy_timeout = %d", cmp.Or(c.BusyTimeout, 5*time.Second).Milliseconds()),
+		"synchronous = " + cmp.Or(c.Synchronous, "NORMAL"),
+		"foreign_keys = " + foreignKeys,
+	}
+	return append(pragmas, c.Pragmas...)
+}
+
+// DBPools are the pools of connections to the SQLite database.
+// SQLite allows a single writer at a time, so Write has a single connection,
+// which makes the writers wait in the pool instead of failing with SQLITE_BUSY.
+// Read has read-only connections, which run concurrently with the writer in the WAL mode.
+type DBPools struct {
+	Write *sql.DB
+	Read  *sql.DB
+}
+
+// OpenDBPools opens the pools of connections to the SQLite database at path, creating it if it does not exist.
+func OpenDBPools(ctx context.Context, path string, cfg DBConfig) (*DBPools, error) {
+	write, err := openWriteDB(ctx, path, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	read := sql.OpenDB(sqliteConnector{
+		dsn:    "file:" + path + "?mode=ro",
+		driver: &sqlite3.SQLiteDriver{ConnectHook: pragmaHook(cfg.pragmas())},
+	})
+	maxReadConns := cmp.Or(cfg.MaxReadConns, max(4, runtime.NumCPU()))
+	read.SetMaxOpenConns(maxReadConns)
+	read.SetMaxIdleConns(maxReadConns)
+	if err := read.PingContext(ctx); err != nil {
+		write.Close()
+		read.Close()
+		return nil, err
+	}
+	return &DBPools{Write: write, Read: read}, nil
+}
+
+// openWriteDB opens the pool of the single connection to write to the SQLite database at path,
+// and sets the journal mode of the database.
+func openWriteDB(ctx context.Context, path string, cfg DBConfig) (*sql.DB, error) {
+	pragmas := append([]string{"journal_mode = " + cmp.Or(cfg.JournalMode, "WAL")}, cfg.pragmas()...)
+	db := sql.OpenDB(sqliteConnector{
+		// take the write lock when a transaction begins, not to fail when it upgrades its read lock
+		dsn:    "file:" + path + "?_txlock=immediate",
+		driver: &sqlite3.SQLiteDriver{ConnectHook: pragmaHook(pragmas)},
+	})
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close closes both of the pools.
+func (p *DBPools) Close() error {
+	return cmp.Or(p.Write.Close(), p.Read.Close())
+}
+
+// DBPoolStats are the statistics of a pool of connections, for monitoring.
+type DBPoolStats struct {
+	MaxOpenConnections  int     `json:"max_open_connections"`
+	OpenConnections     int     `json:"open_connections"`
+	InUse               int     `json:"in_use"`
+	Idle                int     `json:"idle"`
+	WaitCount           int64   `json:"wait_count"`
+	WaitDurationSeconds float64 `json:"wait_duration_seconds"`
+	MaxIdleClosed       int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed   int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed   int64   `json:"max_lifetime_closed"`
+}
+
+// Stats returns the statistics of the pools by their names.
+func (p *DBPools) Stats() map[string]DBPoolStats {
+	return map[string]DBPoolStats{
+		"write": newDBPoolStats(p.Write.Stats()),
+		"read":  newDBPoolStats(p.Read.Stats()),
+	}
+}
+
+func newDBPoolStats(s sql.DBStats) DBPoolStats {
+	return DBPoolStats{
+		MaxOpenConnections:  s.MaxOpenConnections,
+		OpenConnections:     s.OpenConnections,
+		InUse:               s.InUse,
+		Idle:                s.Idle,
+		WaitCount:           s.WaitCount,
+		WaitDurationSeconds: s.WaitDuration.Seconds(),
+		MaxIdleClosed:       s.MaxIdleClosed,
+		MaxIdleTimeClosed:   s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:   s.MaxLifetimeClosed,
+	}
+}
+
+// sqliteConnector is a driver.Connector opening the connections to dsn with driver,
+// which lets every pool have its own ConnectHook without registering a driver for it.
+type sqliteConnector struct {
+	dsn    string
+	driver *sqlite3.SQLiteDriver
+}
+
+func (c sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// pragmaHook returns a ConnectHook applying pragmas on a new connection.
+func pragmaHook(pragmas []string) func(*sqlite3.SQLiteConn) error {
+	return func(conn *sqlite3.SQLiteConn) error {
+		for _, pragma := range pragmas {
+			if _, err := conn.Exec("PRAGMA "+pragma, nil); err != nil {
+				return fmt.Errorf("failed to apply pragma %q: %w", strings.TrimSpace(pragma), err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// openTestDBPools opens the pools to a new migrated database.
+func openTestDBPools(t testing.TB, cfg DBConfig) *DBPools {
+	t.Helper()
+
+	pools, err := OpenDBPools(t.Context(), filepath.Join(t.TempDir(), "mercari.sqlite3"), cfg)
+	if err != nil {
+		t.Fatalf("failed to open pools: %v", err)
+	}
+	t.Cleanup(func() { pools.Close() })
+	if _, err := migrate(t.Context(), pools.Write); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return pools
+}
+
+func TestOpenDBPools(t *testing.T) {
+	t.Parallel()
+
+	pools := openTestDBPools(t, DBConfig{Pragmas: []string{"cache_size = -1000"}})
+
+	for name, db := range map[string]*sql.DB{"write": pools.Write, "read": pools.Read} {
+		var journalMode string
+		var foreignKeys, busyTimeout, cacheSize int
+		err := db.QueryRow(`SELECT journal_mode, foreign_keys, timeout, cache_size
+            FROM pragma_journal_mode, pragma_foreign_keys, pragma_busy_timeout, pragma_cache_size`).
+			Scan(&journalMode, &foreignKeys, &busyTimeout, &cacheSize)
+		if err != nil {
+			t.Fatalf("%s: failed to get pragmas: %v", name, err)
+		}
+		if journalMode != "wal" || foreignKeys != 1 || busyTimeout != 5000 || cacheSize != -1000 {
+			t.Errorf("%s: unexpected pragmas: journal_mode=%s foreign_keys=%d busy_timeout=%d cache_size=%d",
+				name, journalMode, foreignKeys, busyTimeout, cacheSize)
+		}
+	}
+
+	if _, err := pools.Read.Exec("INSERT INTO categories (name) VALUES ('fashion')"); err == nil {
+		t.Error("expected the read pool to be read-only")
+	}
+	// the foreign keys are enforced
+	if err := NewLikeRepository(pools.Write).Like(t.Context(), 1, 1); err == nil {
+		t.Error("expected liking a missing item to fail")
+	}
+	if _, err := pools.Write.Exec("INSERT INTO likes (user_id, item_id) VALUES (1, 1)"); err == nil {
+		t.Error("expected the foreign key constraint to fail")
+	}
+
+	stats := pools.Stats()
+	if stats["write"].MaxOpenConnections != 1 || stats["read"].MaxOpenConnections < 4 {
+		t.Errorf("unexpected pool sizes: %+v", stats)
+	}
+}
+
+func TestDBPoolsConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	pools := openTestDBPools(t, DBConfig{})
+	repo := newTestItemRepository(t, pools.Write, WithReadDB(pools.Read))
+
+	// the writers wait for the single connection instead of failing with SQLITE_BUSY,
+	// while the readers keep reading
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for i := range n {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Insert(t.Context(), &Item{Name: fmt.Sprintf("item%d", i), Category: fmt.Sprintf("category%d", i%3), Image: "default.jpg"})
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.LoadItems(t.Context(), 0)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	items, err := repo.LoadItems(t.Context(), 0)
+	if err != nil {
+		t.Fatalf("failed to load items: %v", err)
+	}
+	if len(items) != n {
+		t.Errorf("expected %d items, got %d", n, len(items))
+	}
+}
+
+func TestGetDBStatsAdminOnly(t *testing.T) {
+	t.Parallel()
+
+	pools := openTestDBPools(t, DBConfig{})
+	userRepo := NewUserRepository(pools.Write)
+	admin := &User{Name: "admin", Email: "admin@example.com", IsAdmin: true}
+	if err := userRepo.Insert(t.Context(), admin); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+	h := &Handlers{userRepo: userRepo, adminToken: "secret", dbStats: pools.Stats}
+
+	for token, wantCode := range map[string]int{"": http.StatusForbidden, "wrong": http.StatusForbidden, "secret": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/db", nil)
+		req.Header.Set(userIDHeader, strconv.Itoa(admin.ID))
+		req.Header.Set(adminTokenHeader, token)
+		rr := httptest.NewRecorder()
+		h.GetDBStats(rr, req)
+		if rr.Code != wantCode {
+			t.Errorf("token %q: expected status %d, got %d", token, wantCode, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"sync"
+)
+
+const (
+	eventItemCreated = "item.created"
+	eventItemUpdated = "item.updated"
+	// eventItemSold can be subscribed to by the webhooks, but nothing publishes it yet,
+	// since an item has no sold status to transition to. The purchase flow has to publish it.
+	eventItemSold = "item.sold"
+	// eventItemStatusChanged is published when the moderation puts an item in review, or an admin approves or rejects it.
+	eventItemStatusChanged = "item.status_changed"
+
+	// eventHistorySize is the number of recent events kept for clients reconnecting with Last-Event-ID.
+	eventHistorySize = 256
+	// subscriberBufferSize is the number of events buffered per subscriber before events are dropped.
+	subscriberBufferSize = 16
+)
+
+// ItemEvent is an event about a change of an item.
+type ItemEvent struct {
+	// ID is a sequence number increasing with each event, used as the SSE event ID.
+	ID     int64  `json:"-"`
+	Type   string `json:"type"`
+	ItemID int    `json:"item_id"`
+	Item   *Item  `json:"item"`
+}
+
+// EventBus is an in-process pub/sub for item events.
+// A nil *EventBus is valid and drops every event.
+type EventBus struct {
+	mu          sync.Mutex
+	nextID      int64
+	history     []ItemEvent
+	subscribers map[chan ItemEvent]struct{}
+}
+
+// NewEventBus creates a new EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[chan ItemEvent]struct{})}
+}
+
+// Publish delivers an event to all the subscribers.
+// Slow subscribers whose buffer is full miss the event instead of blocking the publisher.
+func (b *EventBus) Publish(typ string, item *Item) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	ev := ItemEvent{ID: b.nextID, Type: typ, ItemID: item.ID, Item: item}
+	b.history = append(b.history, ev)
+	if len(b.history) > eventHistorySize {
+		b.history = b.history[len(b.history)-eventHistorySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// PublishStatusChange delivers the new status of an item as an eventItemStatusChanged event.
+// The stream is public, so an item which is not published is sent with its ID and status only,
+// which tells the clients to drop it without revealing a name or an image in review.
+func (b *EventBus) PublishStatusChange(item *Item) {
+	if item.Status != itemStatusPublished {
+		item = &Item{ID: item.ID, Status: item.Status}
+	}
+	b.Publish(eventItemStatusChanged, item)
+}
+
+// Subscribe registers a new subscriber.
+// It returns the events published after lastEventID that are still in the history,
+// a channel receiving the following events, and a function to unsubscribe.
+func (b *EventBus) Subscribe(lastEventID int64) ([]ItemEvent, <-chan ItemEvent, func()) {
+	ch := make(chan ItemEvent, subscriberBufferSize)
+	if b == nil {
+		return nil, ch, func() {}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []ItemEvent
+	if lastEventID > 0 {
+		for _, ev := range b.history {
+			if ev.ID > lastEventID {
+				missed = append(missed, ev)
+			}
+		}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, ch)
+	}
+	return missed, ch, unsubscribe
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEventBus(t *testing.T) {
+	t.Parallel()
+
+	bus := NewEventBus()
+	bus.Publish(eventItemCreated, &Item{ID: 1})
+	bus.Publish(eventItemCreated, &Item{ID: 2})
+
+	missed, events, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	var missedIDs []int
+	for _, ev := range missed {
+		missedIDs = append(missedIDs, ev.ItemID)
+	}
+	if diff := cmp.Diff([]int{2}, missedIDs); diff != "" {
+		t.Errorf("unexpected missed events (-want +got):\n%s", diff)
+	}
+
+	bus.Publish(eventItemUpdated, &Item{ID: 3})
+	ev := <-events
+	if ev.ID != 3 || ev.Type != eventItemUpdated || ev.ItemID != 3 {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	unsubscribe()
+	bus.Publish(eventItemCreated, &Item{ID: 4})
+	select {
+	case ev := <-events:
+		t.Errorf("received event after unsubscribing: %+v", ev)
+	default:
+	}
+}
+
+func TestNilEventBus(t *testing.T) {
+	t.Parallel()
+
+	var bus *EventBus
+	bus.Publish(eventItemCreated, &Item{ID: 1})
+	missed, _, unsubscribe := bus.Subscribe(0)
+	unsubscribe()
+	if len(missed) != 0 {
+		t.Errorf("expected no events, got %+v", missed)
+	}
+}
+
+func TestPublishStatusChange(t *testing.T) {
+	t.Parallel()
+
+	bus := NewEventBus()
+	_, events, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	bus.PublishStatusChange(&Item{ID: 1, Name: "replica watch", Image: "a.jpg", Status: itemStatusPendingReview})
+	bus.PublishStatusChange(&Item{ID: 2, Name: "jacket", Status: itemStatusPublished})
+
+	// the item which is not published is not revealed to the public stream
+	want := []*Item{{ID: 1, Status: itemStatusPendingReview}, {ID: 2, Name: "jacket", Status: itemStatusPublished}}
+	for _, w := range want {
+		ev := <-events
+		if ev.Type != eventItemStatusChanged || ev.ItemID != w.ID {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+		if diff := cmp.Diff(w, ev.Item); diff != "" {
+			t.Errorf("unexpected item (-want +got):\n%s", diff)
+		}
+	}
+}
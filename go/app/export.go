@@ -0,0 +1,91 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatTSV    = "tsv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportColumns is the header of the CSV and TSV exports.
+var exportColumns = []string{"id", "name", "category", "image", "seller_id", "status", "publish_at", "like_count", "comment_count"}
+
+// exportContentTypes is the Content-Type of each export format.
+var exportContentTypes = map[string]string{
+	exportFormatCSV:    "text/csv; charset=utf-8",
+	exportFormatTSV:    "text/tab-separated-values; charset=utf-8",
+	exportFormatNDJSON: "application/x-ndjson",
+}
+
+// itemExporter writes items one by one in an export format.
+type itemExporter interface {
+	Write(item *Item) error
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+}
+
+// newItemExporter creates an itemExporter for the format, writing the header if the format has one.
+func newItemExporter(w io.Writer, format string) (itemExporter, error) {
+	switch format {
+	case exportFormatCSV, exportFormatTSV:
+		cw := csv.NewWriter(w)
+		if format == exportFormatTSV {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return &csvItemExporter{w: cw}, nil
+	case exportFormatNDJSON:
+		return &ndjsonItemExporter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("format must be %s, %s or %s", exportFormatCSV, exportFormatTSV, exportFormatNDJSON)
+	}
+}
+
+type csvItemExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvItemExporter) Write(item *Item) error {
+	publishAt := ""
+	if item.PublishAt != nil {
+		publishAt = item.PublishAt.UTC().Format(time.RFC3339)
+	}
+	return e.w.Write([]string{
+		strconv.Itoa(item.ID),
+		item.Name,
+		item.Category,
+		item.Image,
+		strconv.Itoa(item.SellerID),
+		item.Status,
+		publishAt,
+		strconv.Itoa(item.LikeCount),
+		strconv.Itoa(item.CommentCount),
+	})
+}
+
+func (e *csvItemExporter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonItemExporter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonItemExporter) Write(item *Item) error {
+	return e.enc.Encode(item)
+}
+
+func (e *ndjsonItemExporter) Flush() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestItemExporter(t *testing.T) {
+	t.Parallel()
+
+	publishAt := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	items := []*Item{
+		{ID: 1, Name: "jacket, warm", Category: "fashion", Image: "a.jpg", SellerID: 10, Status: itemStatusPublished, PublishAt: &publishAt, LikeCount: 2},
+		{ID: 2, Name: "shoes", Category: "fashion", Status: itemStatusPublished},
+	}
+
+	cases := map[string]struct {
+		format string
+		want   string
+	}{
+		"csv": {
+			format: exportFormatCSV,
+			want: "id,name,category,image,seller_id,status,publish_at,like_count,comment_count\n" +
+				"1,\"jacket, warm\",fashion,a.jpg,10,published,2025-04-01T12:00:00Z,2,0\n" +
+				"2,shoes,fashion,,0,published,,0,0\n",
+		},
+		"tsv": {
+			format: exportFormatTSV,
+			want: "id\tname\tcategory\timage\tseller_id\tstatus\tpublish_at\tlike_count\tcomment_count\n" +
+				"1\tjacket, warm\tfashion\ta.jpg\t10\tpublished\t2025-04-01T12:00:00Z\t2\t0\n" +
+				"2\tshoes\tfashion\t\t0\tpublished\t\t0\t0\n",
+		},
+		"ndjson": {
+			format: exportFormatNDJSON,
+			want: `{"id":1,"name":"jacket, warm","category":"fashion","image":"a.jpg","seller_id":10,"status":"published","publish_at":"2025-04-01T12:00:00Z","like_count":2,"comment_count":0}` + "\n" +
+				`{"id":2,"name":"shoes","category":"fashion","image":"","status":"published","like_count":0,"comment_count":0}` + "\n",
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			exporter, err := newItemExporter(&buf, tt.format)
+			if err != nil {
+				t.Fatalf("failed to create exporter: %v", err)
+			}
+			for _, item := range items {
+				if err := exporter.Write(item); err != nil {
+					t.Fatalf("failed to write item: %v", err)
+				}
+			}
+			if err := exporter.Flush(); err != nil {
+				t.Fatalf("failed to flush: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("unexpected export:\nwant: %q\ngot:  %q", tt.want, buf.String())
+			}
+		})
+	}
+
+	if _, err := newItemExporter(&bytes.Buffer{}, "parquet"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
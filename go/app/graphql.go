@@ -0,0 +1,433 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const (
+	// maxGraphQLComplexity is the maximum complexity of a query, see graphQLComplexity.
+	maxGraphQLComplexity = 1000
+	// graphQLListFactor is the number of the elements assumed for a list field when computing the complexity.
+	graphQLListFactor = 10
+)
+
+// graphQLRequest is the state of a GraphQL request shared by its resolvers through the context.
+type graphQLRequest struct {
+	h        *Handlers
+	viewerID int
+
+	// the loaders batch the fields of the same level into a single query, instead of one query per item
+	categories    *batchLoader[string, *Category]
+	categoryItems *batchLoader[string, []*Item]
+	sellers       *batchLoader[int, *User]
+	liked         *batchLoader[int, bool]
+}
+
+type graphQLRequestKey struct{}
+
+func newGraphQLRequest(h *Handlers, viewerID int) *graphQLRequest {
+	return &graphQLRequest{
+		h:        h,
+		viewerID: viewerID,
+		categories: newBatchLoader(func(ctx context.Context, _ []string) (map[string]*Category, error) {
+			// the categories are few, so all of them are loaded at once
+			categories, err := h.itemRepo.LoadCategories(ctx)
+			if err != nil {
+				return nil, err
+			}
+			m := make(map[string]*Category, len(categories))
+			for _, c := range categories {
+				m[c.Name] = c
+			}
+			return m, nil
+		}),
+		categoryItems: newBatchLoader(func(ctx context.Context, names []string) (map[string][]*Item, error) {
+			items, err := h.itemRepo.LoadItemsInCategories(ctx, names, viewerID)
+			if err != nil {
+				return nil, err
+			}
+			m := make(map[string][]*Item, len(names))
+			for _, item := range items {
+				m[item.Category] = append(m[item.Category], item)
+			}
+			return m, nil
+		}),
+		sellers: newBatchLoader(func(ctx context.Context, ids []int) (map[int]*User, error) {
+			users, err := h.userRepo.LoadUsers(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			m := make(map[int]*User, len(users))
+			for _, u := range users {
+				m[u.ID] = u
+			}
+			return m, nil
+		}),
+		liked: newBatchLoader(func(ctx context.Context, ids []int) (map[int]bool, error) {
+			likedIDs, err := h.likeRepo.LoadLikedItemIDs(ctx, viewerID, ids)
+			if err != nil {
+				return nil, err
+			}
+			m := make(map[int]bool, len(likedIDs))
+			for _, id := range likedIDs {
+				m[id] = true
+			}
+			return m, nil
+		}),
+	}
+}
+
+func graphQLRequestFrom(ctx context.Context) *graphQLRequest {
+	return ctx.Value(graphQLRequestKey{}).(*graphQLRequest)
+}
+
+// batchLoader loads values by keys in batches.
+// Load only queues the key and returns a thunk, which the executor calls after resolving all the fields of the same level,
+// so the first call fetches the values of all the keys queued until then.
+type batchLoader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(context.Context, []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{fetch: fetch, queued: make(map[K]bool), values: make(map[K]V), errs: make(map[K]error)}
+}
+
+// Load queues the key, and returns the thunk returning its value, or the zero value if fetch did not return it.
+func (l *batchLoader[K, V]) Load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+			values, err := l.fetch(ctx, keys)
+			for _, k := range keys {
+				if err != nil {
+					l.errs[k] = err
+				} else if _, ok := values[k]; !ok {
+					// remember the missing keys too, not to fetch them again
+					var zero V
+					l.values[k] = zero
+				}
+			}
+			for k, v := range values {
+				l.values[k] = v
+			}
+		}
+		return l.values[key], l.errs[key]
+	}
+}
+
+// thunk converts the thunk of a loader to the one the executor resolves lazily.
+func thunk[V any](load func() (V, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		return load()
+	}
+}
+
+var graphQLUserType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	// the email is private, so it is not in the schema
+	Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var graphQLCategoryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Category",
+	Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var graphQLItemType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Item",
+	Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"category": &graphql.Field{
+			Type: graphQLCategoryType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				item := p.Source.(*Item)
+				return thunk(graphQLRequestFrom(p.Context).categories.Load(p.Context, item.Category)), nil
+			},
+		},
+		"image": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"imageUrl": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The signed URL of the image, only set in the signed image URL mode.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				item := graphQLRequestFrom(p.Context).h.imageSigner.withImageURL(p.Source.(*Item))
+				if item.ImageURL == "" {
+					return nil, nil
+				}
+				return item.ImageURL, nil
+			},
+		},
+		"status":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"publishAt":    &graphql.Field{Type: graphql.DateTime},
+		"likeCount":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"commentCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"liked": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Whether the viewer likes the item. It is always false for an anonymous viewer.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				req := graphQLRequestFrom(p.Context)
+				if req.viewerID == 0 {
+					return false, nil
+				}
+				return thunk(req.liked.Load(p.Context, p.Source.(*Item).ID)), nil
+			},
+		},
+		"seller": &graphql.Field{
+			Type:        graphQLUserType,
+			Description: "The seller of the item, which is null if the seller is not a registered user.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				item := p.Source.(*Item)
+				if item.SellerID == 0 {
+					return nil, nil
+				}
+				return thunk(graphQLRequestFrom(p.Context).sellers.Load(p.Context, item.SellerID)), nil
+			},
+		},
+	},
+})
+
+var graphQLQueryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"items": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphQLItemType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				req := graphQLRequestFrom(p.Context)
+				items, err := req.h.itemRepo.LoadItems(p.Context, req.viewerID)
+				if items == nil && err == nil {
+					items = []*Item{}
+				}
+				return items, err
+			},
+		},
+		"item": &graphql.Field{
+			Type: graphQLItemType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				req := graphQLRequestFrom(p.Context)
+				item, err := req.h.itemRepo.LoadItem(p.Context, p.Args["id"].(int), req.viewerID)
+				if errors.Is(err, errItemNotFound) {
+					return nil, nil
+				}
+				return item, err
+			},
+		},
+		"search": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphQLItemType))),
+			Args: graphql.FieldConfigArgument{
+				"keyword": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				req := graphQLRequestFrom(p.Context)
+				items, err := req.h.itemRepo.SearchItems(p.Context, p.Args["keyword"].(string), req.viewerID)
+				if items == nil && err == nil {
+					items = []*Item{}
+				}
+				return items, err
+			},
+		},
+		"categories": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphQLCategoryType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return graphQLRequestFrom(p.Context).h.itemRepo.LoadCategories(p.Context)
+			},
+		},
+	},
+})
+
+// graphQLSchema is the schema of GET and POST /graphql.
+var graphQLSchema = func() graphql.Schema {
+	// the items of a category refer back to the item type, so the field is added after both types are defined
+	graphQLCategoryType.AddFieldConfig("items", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphQLItemType))),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			load := graphQLRequestFrom(p.Context).categoryItems.Load(p.Context, p.Source.(*Category).Name)
+			return func() (interface{}, error) {
+				items, err := load()
+				if items == nil && err == nil {
+					items = []*Item{}
+				}
+				return items, err
+			}, nil
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: graphQLQueryType})
+	if err != nil {
+		panic(fmt.Sprintf("invalid GraphQL schema: %v", err))
+	}
+	return schema
+}()
+
+// graphQLComplexity returns the complexity of the operation in the document, to reject the queries too expensive to run.
+// Every field costs 1, and the fields selected under a list cost graphQLListFactor times,
+// since they are resolved for each of its elements.
+func graphQLComplexity(schema graphql.Schema, doc *ast.Document, operationName string) int {
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operation == nil || (def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		}
+	}
+	if operation == nil {
+		return 0
+	}
+
+	var complexity func(set *ast.SelectionSet, parent *graphql.Object) int
+	complexity = func(set *ast.SelectionSet, parent *graphql.Object) int {
+		if set == nil || parent == nil {
+			return 0
+		}
+		total := 0
+		for _, selection := range set.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				total++
+				field, ok := parent.Fields()[selection.Name.Value]
+				if !ok {
+					// __typename and the introspection fields
+					continue
+				}
+				factor, fieldType := 1, field.Type
+				for {
+					if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+						fieldType = nonNull.OfType
+					} else if list, ok := fieldType.(*graphql.List); ok {
+						factor *= graphQLListFactor
+						fieldType = list.OfType
+					} else {
+						break
+					}
+				}
+				object, _ := fieldType.(*graphql.Object)
+				total += factor * complexity(selection.SelectionSet, object)
+			case *ast.InlineFragment:
+				total += complexity(selection.SelectionSet, parent)
+			case *ast.FragmentSpread:
+				// the validation rejects the cycles of the fragments before this is called
+				if fragment, ok := fragments[selection.Name.Value]; ok {
+					total += complexity(fragment.SelectionSet, parent)
+				}
+			}
+		}
+		return total
+	}
+	return complexity(operation.SelectionSet, schema.QueryType())
+}
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// parseGraphQLRequest parses the JSON body of POST /graphql, or the query parameters of GET /graphql.
+func parseGraphQLRequest(r *http.Request) (*GraphQLRequest, error) {
+	req := &GraphQLRequest{}
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return nil, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	if req.Query == "" {
+		return nil, errors.New("query is required")
+	}
+	return req, nil
+}
+
+// GraphQL is a handler to run a GraphQL query over the items and categories for GET and POST /graphql.
+// The fields of the related objects are loaded in batches, so the number of the queries to the database
+// depends on the depth of the query instead of the number of the items.
+func (s *Handlers) GraphQL(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := parseOptionalUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := parseGraphQLRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := s.runGraphQL(r.Context(), req, viewerID)
+	w.Header().Set("Content-Type", "application/json")
+	if result.Data == nil && result.HasErrors() {
+		// the query was not run
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// runGraphQL parses, validates and checks the complexity of the query before running it.
+func (s *Handlers) runGraphQL(ctx context.Context, req *GraphQLRequest, viewerID int) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if validation := graphql.ValidateDocument(&graphQLSchema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+	if complexity := graphQLComplexity(graphQLSchema, doc, req.OperationName); complexity > maxGraphQLComplexity {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(fmt.Errorf("query complexity %d exceeds the limit %d", complexity, maxGraphQLComplexity))}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphQLSchema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(ctx, graphQLRequestKey{}, newGraphQLRequest(s, viewerID)),
+	})
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+)
+
+// postGraphQL runs the query on POST /graphql as the viewer, and returns the status code and the decoded response.
+func postGraphQL(t *testing.T, h *Handlers, query string, viewerID string) (int, map[string]interface{}) {
+	t.Helper()
+
+	body, err := json.Marshal(GraphQLRequest{Query: query})
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	if viewerID != "" {
+		req.Header.Set(userIDHeader, viewerID)
+	}
+	rr := httptest.NewRecorder()
+	h.GraphQL(rr, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rr.Body.String(), err)
+	}
+	return rr.Code, resp
+}
+
+func TestGraphQLBatchedLoading(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	mockLR := NewMockLikeRepository(ctrl)
+	mockUR := NewMockUserRepository(ctrl)
+	items := []*Item{
+		{ID: 1, Name: "jacket", Category: "fashion", SellerID: 10},
+		{ID: 2, Name: "shirt", Category: "fashion", SellerID: 20},
+		{ID: 3, Name: "phone", Category: "electronics", SellerID: 10},
+	}
+	// a single query per field, however many items there are
+	mockIR.EXPECT().LoadItems(gomock.Any(), 10).Return(items, nil)
+	mockIR.EXPECT().LoadCategories(gomock.Any()).Return([]*Category{{ID: 1, Name: "fashion"}, {ID: 2, Name: "electronics"}}, nil).Times(1)
+	mockUR.EXPECT().LoadUsers(gomock.Any(), []int{10, 20}).Return([]*User{{ID: 10, Name: "alice"}}, nil).Times(1)
+	mockLR.EXPECT().LoadLikedItemIDs(gomock.Any(), 10, []int{1, 2, 3}).Return([]int{2}, nil).Times(1)
+	h := &Handlers{itemRepo: mockIR, likeRepo: mockLR, userRepo: mockUR}
+
+	code, resp := postGraphQL(t, h, `{ items { id category { name } liked seller { name } } }`, "10")
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %v", http.StatusOK, code, resp)
+	}
+	want := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"id": 1.0, "category": map[string]interface{}{"name": "fashion"}, "liked": false, "seller": map[string]interface{}{"name": "alice"}},
+				map[string]interface{}{"id": 2.0, "category": map[string]interface{}{"name": "fashion"}, "liked": true, "seller": nil},
+				map[string]interface{}{"id": 3.0, "category": map[string]interface{}{"name": "electronics"}, "liked": false, "seller": map[string]interface{}{"name": "alice"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, resp); diff != "" {
+		t.Errorf("unexpected response (-want +got):\n%s", diff)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query string
+
+		wantError string
+	}{
+		"syntax error": {
+			query:     `{ items { id }`,
+			wantError: "Syntax Error",
+		},
+		"unknown field": {
+			query:     `{ items { price } }`,
+			wantError: `Cannot query field "price"`,
+		},
+		"too complex": {
+			// 1 + 10 * (1 + 1 * (1 + 10 * (1 + 1 * (1 + 10 * 1)))) = 1221
+			query:     `{ items { category { items { category { items { id } } } } } }`,
+			wantError: "query complexity 1221 exceeds the limit",
+		},
+		"too complex with fragments": {
+			query:     `{ items { ...nested } } fragment nested on Item { category { items { category { items { id } } } } }`,
+			wantError: "query complexity 1221 exceeds the limit",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// the repositories are not called, since the query is rejected before running it
+			ctrl := gomock.NewController(t)
+			h := &Handlers{itemRepo: NewMockItemRepository(ctrl)}
+
+			code, resp := postGraphQL(t, h, tt.query, "")
+			if code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, code)
+			}
+			errs, _ := resp["errors"].([]interface{})
+			if len(errs) == 0 {
+				t.Fatalf("expected errors, got %v", resp)
+			}
+			if msg := errs[0].(map[string]interface{})["message"].(string); !strings.Contains(msg, tt.wantError) {
+				t.Errorf("expected error containing %q, got %q", tt.wantError, msg)
+			}
+		})
+	}
+}
+
+func TestGraphQLE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := context.Background()
+	itemRepo := newTestItemRepository(t, db)
+	likeRepo := NewLikeRepository(db)
+	userRepo := NewUserRepository(db)
+	seller := &User{Name: "alice", Email: "alice@example.com"}
+	if err := userRepo.Insert(ctx, seller); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+	for _, item := range []*Item{
+		{Name: "jacket", Category: "fashion", Image: "default.jpg", SellerID: seller.ID, Status: itemStatusPublished},
+		{Name: "phone", Category: "electronics", Image: "default.jpg", SellerID: seller.ID, Status: itemStatusPublished},
+		{Name: "shirt", Category: "fashion", Image: "default.jpg", SellerID: seller.ID, Status: itemStatusDraft},
+	} {
+		if err := itemRepo.Insert(ctx, item); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+	}
+	if err := likeRepo.Like(ctx, 99, 1); err != nil {
+		t.Fatalf("failed to like item: %v", err)
+	}
+	h := &Handlers{itemRepo: itemRepo, likeRepo: likeRepo, userRepo: userRepo}
+
+	// the draft is only visible to its seller
+	query := `{ categories { name items { name likeCount liked seller { name } } } }`
+	code, resp := postGraphQL(t, h, query, "99")
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %v", http.StatusOK, code, resp)
+	}
+	want := map[string]interface{}{
+		"data": map[string]interface{}{
+			"categories": []interface{}{
+				map[string]interface{}{"name": "fashion", "items": []interface{}{
+					map[string]interface{}{"name": "jacket", "likeCount": 1.0, "liked": true, "seller": map[string]interface{}{"name": "alice"}},
+				}},
+				map[string]interface{}{"name": "electronics", "items": []interface{}{
+					map[string]interface{}{"name": "phone", "likeCount": 0.0, "liked": false, "seller": map[string]interface{}{"name": "alice"}},
+				}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, resp); diff != "" {
+		t.Errorf("unexpected response (-want +got):\n%s", diff)
+	}
+
+	// GET /graphql takes the query as a parameter
+	req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`{ item(id: 3) { name } }`), nil)
+	req.Header.Set(userIDHeader, "1")
+	rr := httptest.NewRecorder()
+	h.GraphQL(rr, req)
+	if got, want := strings.TrimSpace(rr.Body.String()), `{"data":{"item":{"name":"shirt"}}}`; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
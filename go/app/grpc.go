@@ -0,0 +1,197 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"mercari-build-training/app/itempb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxGRPCImageSize is the maximum size of an image sent to AddItem, the same as the multipart form of POST /items.
+const maxGRPCImageSize = 32 << 20
+
+// itemServer is the gRPC API of the items, serving the same repository and image store as the handlers.
+type itemServer struct {
+	itempb.UnimplementedItemServiceServer
+	h *Handlers
+}
+
+// newGRPCServer returns a gRPC server of the items served by the handlers.
+func newGRPCServer(h *Handlers) *grpc.Server {
+	server := grpc.NewServer()
+	itempb.RegisterItemServiceServer(server, &itemServer{h: h})
+	return server
+}
+
+// grpcUserID returns the user ID in the x-user-id metadata, or 0 if it is missing and not required.
+func grpcUserID(ctx context.Context, required bool) (int, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(strings.ToLower(userIDHeader))
+	if len(values) == 0 && !required {
+		return 0, nil
+	}
+	if len(values) == 1 {
+		if userID, err := strconv.Atoi(values[0]); err == nil && userID > 0 {
+			return userID, nil
+		}
+	}
+	return 0, status.Errorf(codes.InvalidArgument, "valid %s metadata is required", strings.ToLower(userIDHeader))
+}
+
+// grpcRequestID returns the request ID in the x-request-id metadata, or a generated one if it is missing or invalid.
+func grpcRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(strings.ToLower(requestIDHeader)); len(values) == 1 && validRequestID(values[0]) {
+		return values[0]
+	}
+	return rand.Text()
+}
+
+func (s *itemServer) AddItem(stream itempb.ItemService_AddItemServer) error {
+	ctx := stream.Context()
+	sellerID, err := grpcUserID(ctx, true)
+	if err != nil {
+		return err
+	}
+	requestID := grpcRequestID(ctx)
+	if err := stream.SetHeader(metadata.Pairs(strings.ToLower(requestIDHeader), requestID)); err != nil {
+		return err
+	}
+	ctx = withAuditActor(ctx, auditActor{UserID: sellerID, RequestID: requestID})
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	newItem := req.GetItem()
+	if newItem == nil {
+		return status.Error(codes.InvalidArgument, "the first message must have the item")
+	}
+	if newItem.GetName() == "" || newItem.GetCategory() == "" {
+		return status.Error(codes.InvalidArgument, "name and category are required")
+	}
+
+	var image []byte
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetItem() != nil {
+			return status.Error(codes.InvalidArgument, "only the first message can have the item")
+		}
+		if len(image)+len(req.GetImageChunk()) > maxGRPCImageSize {
+			return status.Errorf(codes.InvalidArgument, "image must be at most %d bytes", maxGRPCImageSize)
+		}
+		image = append(image, req.GetImageChunk()...)
+	}
+
+	fileName, err := s.h.storeImage(ctx, image)
+	if errors.Is(err, errInvalidImage) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		slog.Error("failed to store image: ", "error", err)
+		return status.Error(codes.Internal, "failed to store image")
+	}
+	item := &Item{
+		Name:     newItem.GetName(),
+		Category: newItem.GetCategory(),
+		Image:    fileName,
+		SellerID: sellerID,
+		Status:   itemStatusDraft,
+	}
+	s.h.moderation.check(item)
+	if err := s.h.itemRepo.Insert(ctx, item); err != nil {
+		slog.Error("failed to store item", "error", err)
+		return status.Error(codes.Internal, "failed to insert item")
+	}
+	return stream.SendAndClose(s.toItemPB(item))
+}
+
+func (s *itemServer) GetItems(ctx context.Context, _ *itempb.GetItemsRequest) (*itempb.GetItemsResponse, error) {
+	viewerID, err := grpcUserID(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.h.itemRepo.LoadItems(ctx, viewerID)
+	if err != nil {
+		slog.Error("failed to load items: ", "error", err)
+		return nil, status.Error(codes.Internal, "failed to load items")
+	}
+	return &itempb.GetItemsResponse{Items: s.toItemPBs(items)}, nil
+}
+
+func (s *itemServer) GetItem(ctx context.Context, req *itempb.GetItemRequest) (*itempb.Item, error) {
+	viewerID, err := grpcUserID(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.h.itemRepo.LoadItem(ctx, int(req.GetId()), viewerID)
+	if errors.Is(err, errItemNotFound) {
+		return nil, status.Error(codes.NotFound, "item not found")
+	}
+	if err != nil {
+		slog.Error("failed to load item: ", "error", err)
+		return nil, status.Error(codes.Internal, "failed to load item")
+	}
+	return s.toItemPB(item), nil
+}
+
+func (s *itemServer) Search(ctx context.Context, req *itempb.SearchRequest) (*itempb.SearchResponse, error) {
+	if req.GetKeyword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "keyword is required")
+	}
+	viewerID, err := grpcUserID(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.h.itemRepo.SearchItems(ctx, req.GetKeyword(), viewerID)
+	if err != nil {
+		slog.Error("failed to search items: ", "error", err)
+		return nil, status.Error(codes.Internal, "failed to search items")
+	}
+	return &itempb.SearchResponse{Items: s.toItemPBs(items)}, nil
+}
+
+// toItemPB converts an item to its message, with the signed URL of its image in the signed mode.
+func (s *itemServer) toItemPB(item *Item) *itempb.Item {
+	item = s.h.imageSigner.withImageURL(item)
+	pb := &itempb.Item{
+		Id:           int64(item.ID),
+		Name:         item.Name,
+		Category:     item.Category,
+		Image:        item.Image,
+		ImageUrl:     item.ImageURL,
+		SellerId:     int64(item.SellerID),
+		Status:       item.Status,
+		LikeCount:    int64(item.LikeCount),
+		CommentCount: int64(item.CommentCount),
+	}
+	if item.PublishAt != nil {
+		pb.PublishAt = timestamppb.New(*item.PublishAt)
+	}
+	return pb
+}
+
+func (s *itemServer) toItemPBs(items []*Item) []*itempb.Item {
+	pbs := make([]*itempb.Item, len(items))
+	for i, item := range items {
+		pbs[i] = s.toItemPB(item)
+	}
+	return pbs
+}
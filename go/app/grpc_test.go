@@ -0,0 +1,173 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mercari-build-training/app/itempb"
+
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestItemClient serves the gRPC API of the handlers in memory and returns its client.
+func newTestItemClient(t *testing.T, h *Handlers) itempb.ItemServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := newGRPCServer(h)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return itempb.NewItemServiceClient(conn)
+}
+
+func TestGRPCAddItem(t *testing.T) {
+	t.Parallel()
+
+	photo := encodePNG(t, testPhoto(64, 48, 7, 5))
+	sanitized, err := sanitizeImage(photo)
+	if err != nil {
+		t.Fatalf("failed to sanitize image: %v", err)
+	}
+
+	cases := map[string]struct {
+		userID   string
+		requests []*itempb.AddItemRequest
+
+		wantCode codes.Code
+	}{
+		"ok: image in chunks": {
+			userID: "10",
+			requests: []*itempb.AddItemRequest{
+				{Data: &itempb.AddItemRequest_Item{Item: &itempb.AddItemRequest_NewItem{Name: "jacket", Category: "fashion"}}},
+				{Data: &itempb.AddItemRequest_ImageChunk{ImageChunk: photo[:100]}},
+				{Data: &itempb.AddItemRequest_ImageChunk{ImageChunk: photo[100:]}},
+			},
+			wantCode: codes.OK,
+		},
+		"ng: not an image": {
+			userID: "10",
+			requests: []*itempb.AddItemRequest{
+				{Data: &itempb.AddItemRequest_Item{Item: &itempb.AddItemRequest_NewItem{Name: "jacket", Category: "fashion"}}},
+				{Data: &itempb.AddItemRequest_ImageChunk{ImageChunk: []byte("jpeg")}},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		"ng: no user": {
+			requests: []*itempb.AddItemRequest{
+				{Data: &itempb.AddItemRequest_Item{Item: &itempb.AddItemRequest_NewItem{Name: "jacket", Category: "fashion"}}},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		"ng: image before item": {
+			userID: "10",
+			requests: []*itempb.AddItemRequest{
+				{Data: &itempb.AddItemRequest_ImageChunk{ImageChunk: []byte("jpeg")}},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		"ng: empty name": {
+			userID: "10",
+			requests: []*itempb.AddItemRequest{
+				{Data: &itempb.AddItemRequest_Item{Item: &itempb.AddItemRequest_NewItem{Category: "fashion"}}},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			if tt.wantCode == codes.OK {
+				mockIR.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, item *Item) error {
+					item.ID = 1
+					return nil
+				})
+			}
+			imgDir := t.TempDir()
+			client := newTestItemClient(t, &Handlers{imgDirPath: imgDir, itemRepo: mockIR})
+
+			ctx := context.Background()
+			if tt.userID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-user-id", tt.userID)
+			}
+			stream, err := client.AddItem(ctx)
+			if err != nil {
+				t.Fatalf("failed to start stream: %v", err)
+			}
+			for _, req := range tt.requests {
+				if err := stream.Send(req); err != nil {
+					break
+				}
+			}
+			item, err := stream.CloseAndRecv()
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("expected code %v, got %v (%v)", tt.wantCode, got, err)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if item.GetId() != 1 || item.GetSellerId() != 10 || item.GetStatus() != itemStatusDraft {
+				t.Errorf("unexpected item: %v", item)
+			}
+			image, err := os.ReadFile(filepath.Join(imgDir, item.GetImage()))
+			if err != nil || !bytes.Equal(image, sanitized.Data) {
+				t.Errorf("expected the chunks to be stored as the sanitized image (%v)", err)
+			}
+		})
+	}
+}
+
+func TestGRPCGetItem(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	mockIR.EXPECT().LoadItem(gomock.Any(), 1, 10).Return(&Item{ID: 1, Name: "jacket", Category: "fashion", Status: itemStatusPublished}, nil)
+	mockIR.EXPECT().LoadItem(gomock.Any(), 2, 0).Return(nil, errItemNotFound)
+	mockIR.EXPECT().LoadItems(gomock.Any(), 0).Return([]*Item{{ID: 1}, {ID: 3}}, nil)
+	client := newTestItemClient(t, &Handlers{itemRepo: mockIR})
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "10")
+	item, err := client.GetItem(ctx, &itempb.GetItemRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item.GetName() != "jacket" {
+		t.Errorf("unexpected item: %v", item)
+	}
+
+	if _, err := client.GetItem(context.Background(), &itempb.GetItemRequest{Id: 2}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+
+	resp, err := client.GetItems(context.Background(), &itempb.GetItemsRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.GetItems()) != 2 {
+		t.Errorf("expected 2 items, got %d", len(resp.GetItems()))
+	}
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// idempotencyKeyHeader is the header of a key chosen by the client, to retry a request without running it twice.
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader is set on the responses replayed for a retry.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength is the maximum length of an idempotency key, long enough for a UUID with a prefix.
+	maxIdempotencyKeyLength = 255
+	// defaultIdempotencyKeyTTL is how long the responses are replayed by default.
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+	// idempotencyKeyPurgeInterval is the interval to remove the expired keys.
+	idempotencyKeyPurgeInterval = time.Hour
+)
+
+// addItemRequestHash returns the hash identifying the request to add an item,
+// computed from its fields instead of the body, since a retry may encode the multipart form with another boundary.
+func addItemRequestHash(req *AddItemRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n%q\n", req.Name, req.Category)
+	h.Write(req.Image)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// reserveIdempotencyKey reserves the Idempotency-Key header of the request of the user if any,
+// and returns the reserved key to complete with the response, or nil if the header is missing.
+// If the key is used by a previous request, it writes the response to the retry and returns done.
+func (s *Handlers) reserveIdempotencyKey(w http.ResponseWriter, r *http.Request, userID int, requestHash string) (key *IdempotencyKey, done bool) {
+	value := r.Header.Get(idempotencyKeyHeader)
+	if value == "" {
+		return nil, false
+	}
+	if len(value) > maxIdempotencyKeyLength {
+		http.Error(w, fmt.Sprintf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength), http.StatusBadRequest)
+		return nil, true
+	}
+
+	now := time.Now()
+	key = &IdempotencyKey{UserID: userID, Key: value, RequestHash: requestHash, CreatedAt: now}
+	existing, err := s.idempotencyKeyRepo.Reserve(r.Context(), key, now.Add(-s.idempotencyKeyTTL()))
+	switch {
+	case errors.Is(err, errIdempotencyKeyExists):
+		// handled below
+	case err != nil:
+		slog.Error("failed to reserve idempotency key: ", "error", err)
+		http.Error(w, "failed to reserve idempotency key", http.StatusInternalServerError)
+		return nil, true
+	default:
+		return key, false
+	}
+
+	if existing.RequestHash != requestHash {
+		http.Error(w, fmt.Sprintf("%s is already used for a different request", idempotencyKeyHeader), http.StatusUnprocessableEntity)
+		return nil, true
+	}
+	if existing.StatusCode == 0 {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, fmt.Sprintf("the request with the %s is in progress", idempotencyKeyHeader), http.StatusConflict)
+		return nil, true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(existing.StatusCode)
+	if _, err := w.Write(existing.Body); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+	return nil, true
+}
+
+// completeIdempotencyKey records the response of the request for its retries, or releases the key on a server error
+// or an error without a JSON body to replay, so that a retry can run the request again. It does nothing if key is nil.
+func (s *Handlers) completeIdempotencyKey(ctx context.Context, key *IdempotencyKey, statusCode int, body []byte) {
+	if key == nil {
+		return
+	}
+	// the response is recorded even if the client is gone, since the client is likely to retry then
+	ctx = context.WithoutCancel(ctx)
+	if statusCode >= http.StatusInternalServerError || body == nil {
+		if err := s.idempotencyKeyRepo.Release(ctx, key.UserID, key.Key); err != nil {
+			slog.Error("failed to release idempotency key", "error", err)
+		}
+		return
+	}
+	key.StatusCode, key.Body = statusCode, body
+	if err := s.idempotencyKeyRepo.Complete(ctx, key); err != nil {
+		slog.Error("failed to complete idempotency key", "error", err)
+	}
+}
+
+func (s *Handlers) idempotencyKeyTTL() time.Duration {
+	if s.idempotencyTTL > 0 {
+		return s.idempotencyTTL
+	}
+	return defaultIdempotencyKeyTTL
+}
+
+// runIdempotencyKeyPurger removes the keys older than ttl every interval, until ctx is done.
+func runIdempotencyKeyPurger(ctx context.Context, repo IdempotencyKeyRepository, ttl, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := repo.Purge(ctx, now.Add(-ttl))
+			if err != nil {
+				slog.Error("failed to purge idempotency keys", "error", err)
+				continue
+			}
+			slog.Debug("purged idempotency keys", "count", n)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+// newAddItemRequest returns a request of POST /items by user 1, with the Idempotency-Key header unless key is empty.
+// Every request has its own multipart boundary, as a retry of a client would.
+func newAddItemRequest(t *testing.T, key string, fields map[string]string) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			t.Fatalf("failed to write field: %v", err)
+		}
+	}
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/items", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set(userIDHeader, "1")
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestAddItemIdempotencyE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	itemRepo := newTestItemRepository(t, db)
+	keyRepo := NewIdempotencyKeyRepository(db)
+	h := &Handlers{imgDirPath: t.TempDir(), itemRepo: itemRepo, idempotencyKeyRepo: keyRepo}
+	jacket := map[string]string{"name": "jacket", "category": "fashion"}
+
+	first := httptest.NewRecorder()
+	h.AddItem(first, newAddItemRequest(t, "key-1", jacket))
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, first.Code, first.Body)
+	}
+
+	t.Run("retry is replayed", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.AddItem(rr, newAddItemRequest(t, "key-1", jacket))
+		if rr.Code != http.StatusOK || rr.Body.String() != first.Body.String() {
+			t.Errorf("expected the first response %q, got %d %q", first.Body, rr.Code, rr.Body)
+		}
+		if rr.Header().Get(idempotentReplayedHeader) != "true" {
+			t.Errorf("expected the %s header", idempotentReplayedHeader)
+		}
+		items, err := itemRepo.LoadItems(t.Context(), 1)
+		if err != nil {
+			t.Fatalf("failed to load items: %v", err)
+		}
+		if len(items) != 1 {
+			t.Errorf("expected a single item, got %d", len(items))
+		}
+	})
+
+	t.Run("conflicting body", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.AddItem(rr, newAddItemRequest(t, "key-1", map[string]string{"name": "shirt", "category": "fashion"}))
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+	})
+
+	t.Run("in progress", func(t *testing.T) {
+		key := &IdempotencyKey{UserID: 1, Key: "key-2", RequestHash: addItemRequestHash(&AddItemRequest{Name: "jacket", Category: "fashion"}), CreatedAt: time.Now()}
+		if _, err := keyRepo.Reserve(t.Context(), key, time.Now().Add(-time.Hour)); err != nil {
+			t.Fatalf("failed to reserve key: %v", err)
+		}
+		rr := httptest.NewRecorder()
+		h.AddItem(rr, newAddItemRequest(t, "key-2", jacket))
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("keys of other users", func(t *testing.T) {
+		req := newAddItemRequest(t, "key-1", jacket)
+		req.Header.Set(userIDHeader, "2")
+		rr := httptest.NewRecorder()
+		h.AddItem(rr, req)
+		if rr.Code != http.StatusOK || rr.Header().Get(idempotentReplayedHeader) != "" {
+			t.Errorf("expected a new item, got %d %q", rr.Code, rr.Body)
+		}
+	})
+}
+
+func TestAddItemIdempotencyReleasedOnError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	gomock.InOrder(
+		mockIR.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(errors.New("disk I/O error")),
+		mockIR.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil),
+	)
+	h := &Handlers{imgDirPath: t.TempDir(), itemRepo: mockIR, idempotencyKeyRepo: NewIdempotencyKeyRepository(db)}
+
+	// the retry runs the request again, since the first one failed
+	for _, want := range []int{http.StatusInternalServerError, http.StatusOK} {
+		rr := httptest.NewRecorder()
+		h.AddItem(rr, newAddItemRequest(t, "key-1", map[string]string{"name": "jacket", "category": "fashion"}))
+		if rr.Code != want {
+			t.Errorf("expected status %d, got %d", want, rr.Code)
+		}
+	}
+}
+
+func TestIdempotencyKeyRepository(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := context.Background()
+	repo := NewIdempotencyKeyRepository(db)
+	now := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	old := &IdempotencyKey{UserID: 1, Key: "old", RequestHash: "a", CreatedAt: now.Add(-2 * time.Hour)}
+	if _, err := repo.Reserve(ctx, old, now.Add(-3*time.Hour)); err != nil {
+		t.Fatalf("failed to reserve key: %v", err)
+	}
+	old.StatusCode, old.Body = http.StatusOK, []byte(`{"id":1}`)
+	if err := repo.Complete(ctx, old); err != nil {
+		t.Fatalf("failed to complete key: %v", err)
+	}
+
+	existing, err := repo.Reserve(ctx, &IdempotencyKey{UserID: 1, Key: "old", RequestHash: "b", CreatedAt: now}, now.Add(-3*time.Hour))
+	if !errors.Is(err, errIdempotencyKeyExists) {
+		t.Fatalf("expected errIdempotencyKeyExists, got %v", err)
+	}
+	if existing.RequestHash != "a" || existing.StatusCode != http.StatusOK || string(existing.Body) != `{"id":1}` {
+		t.Errorf("unexpected key: %+v", existing)
+	}
+
+	// the expired key is replaced
+	if _, err := repo.Reserve(ctx, &IdempotencyKey{UserID: 1, Key: "old", RequestHash: "b", CreatedAt: now}, now.Add(-time.Hour)); err != nil {
+		t.Errorf("expected the expired key to be replaced, got %v", err)
+	}
+
+	if _, err := repo.Reserve(ctx, &IdempotencyKey{UserID: 1, Key: "new", RequestHash: "c", CreatedAt: now.Add(time.Hour)}, now); err != nil {
+		t.Fatalf("failed to reserve key: %v", err)
+	}
+	if n, err := repo.Purge(ctx, now.Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("expected 1 key to be purged, got %d (%v)", n, err)
+	}
+}
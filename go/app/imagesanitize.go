@@ -0,0 +1,168 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+
+	// the formats of the uploads decoded besides JPEG
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	// sanitizedJPEGQuality is the quality of the JPEG stored for an uploaded image.
+	sanitizedJPEGQuality = 90
+	// maxImagePixels limits the size of a decoded image, since a small file can expand to gigabytes of pixels.
+	maxImagePixels = 50_000_000
+
+	// exifOrientationTag is the tag of the orientation in IFD0 of EXIF.
+	exifOrientationTag = 0x0112
+)
+
+// errInvalidImage is returned for an upload which cannot be stored as an image.
+var errInvalidImage = errors.New("invalid image")
+
+// sanitizedImage is an uploaded image re-encoded without its metadata.
+type sanitizedImage struct {
+	// Data is the JPEG to store, whose hash names the file.
+	Data []byte
+	// Image is the decoded image, upright and on a white background.
+	Image image.Image
+}
+
+// sanitizeImage decodes an uploaded image and re-encodes it as a JPEG, so that no metadata of the upload is stored,
+// such as the GPS location of a phone photo. The EXIF orientation is applied to the pixels, since it is dropped as well.
+// Transparent pixels become white, and only the first frame of an animated GIF is kept.
+func sanitizeImage(data []byte) (*sanitizedImage, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: it must be a JPEG, PNG or GIF (%v)", errInvalidImage, err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: it must be at most %d pixels", errInvalidImage, maxImagePixels)
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidImage, err)
+	}
+
+	// flatten the image onto white, as JPEG has no transparency
+	b := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, b.Min, draw.Over)
+
+	img := flat
+	if format == "jpeg" {
+		img = applyOrientation(flat, exifOrientation(data))
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: sanitizedJPEGQuality}); err != nil {
+		return nil, err
+	}
+	return &sanitizedImage{Data: buf.Bytes(), Image: img}, nil
+}
+
+// exifOrientation returns the orientation in the EXIF of a JPEG from 1 to 8, or 1 if it has none.
+// 1 is upright, and the others are the rotations and the flips to display the image upright.
+func exifOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	// walk the segments until the image data, which starts at the SOS marker
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xff; {
+		marker := data[pos+1]
+		if marker == 0xda {
+			break
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			break
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			if o := tiffOrientation(segment[6:]); o != 0 {
+				return o
+			}
+		}
+		pos += 2 + size
+	}
+	return 1
+}
+
+// tiffOrientation returns the orientation in IFD0 of the TIFF structure of EXIF, or 0 if it has none.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := range entries {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		// the orientation is a SHORT stored in the first bytes of the value
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// applyOrientation returns the image transformed to be upright for the EXIF orientation.
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// the orientations from 5 to 8 swap the width and the height
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+			switch orientation {
+			case 2: // flipped horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated by 180 degrees
+				sx, sy = w-1-x, h-1-y
+			case 4: // flipped vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated clockwise to display
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated counterclockwise to display
+				sx, sy = w-1-y, x
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withEXIF inserts an EXIF segment with the orientation and a GPS note into a JPEG, in the byte order of the TIFF.
+func withEXIF(jpegData []byte, order binary.ByteOrder, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	if order == binary.ByteOrder(binary.LittleEndian) {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	binary.Write(&tiff, order, uint16(42))
+	binary.Write(&tiff, order, uint32(8))
+	// IFD0 with the orientation only, and no next IFD
+	binary.Write(&tiff, order, uint16(1))
+	binary.Write(&tiff, order, []uint16{exifOrientationTag, 3})
+	binary.Write(&tiff, order, uint32(1))
+	binary.Write(&tiff, order, []uint16{orientation, 0})
+	binary.Write(&tiff, order, uint32(0))
+	tiff.WriteString("GPS 35.6586N 139.7454E")
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write(jpegData[:2])
+	out.Write([]byte{0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(jpegData[2:])
+	return out.Bytes()
+}
+
+func TestExifOrientation(t *testing.T) {
+	t.Parallel()
+
+	plain := encodeJPEG(t, testPhoto(16, 8, 7, 5), 90)
+	cases := map[string]struct {
+		data []byte
+
+		want int
+	}{
+		"big endian":    {data: withEXIF(plain, binary.BigEndian, 6), want: 6},
+		"little endian": {data: withEXIF(plain, binary.LittleEndian, 8), want: 8},
+		"no EXIF":       {data: plain, want: 1},
+		"invalid value": {data: withEXIF(plain, binary.BigEndian, 9), want: 1},
+		"truncated":     {data: withEXIF(plain, binary.BigEndian, 6)[:20], want: 1},
+		"not a JPEG":    {data: encodePNG(t, testPhoto(16, 8, 7, 5)), want: 1},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := exifOrientation(tt.data); got != tt.want {
+				t.Errorf("expected orientation %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	t.Parallel()
+
+	// a 3x2 image whose pixels are numbered from 0 to 5 in their red
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := range 6 {
+		src.SetRGBA(i%3, i/3, color.RGBA{R: uint8(i), A: 255})
+	}
+	// the pixels of the upright image row by row
+	cases := map[int][]uint8{
+		1: {0, 1, 2, 3, 4, 5},
+		2: {2, 1, 0, 5, 4, 3},
+		3: {5, 4, 3, 2, 1, 0},
+		4: {3, 4, 5, 0, 1, 2},
+		5: {0, 3, 1, 4, 2, 5},
+		6: {3, 0, 4, 1, 5, 2},
+		7: {5, 2, 4, 1, 3, 0},
+		8: {2, 5, 1, 4, 0, 3},
+	}
+
+	for orientation, want := range cases {
+		dst := applyOrientation(src, orientation)
+		b := dst.Bounds()
+		var got []uint8
+		for y := range b.Dy() {
+			for x := range b.Dx() {
+				got = append(got, dst.RGBAAt(x, y).R)
+			}
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("orientation %d: expected %v, got %v", orientation, want, got)
+		}
+		if orientation >= 5 && (b.Dx() != 2 || b.Dy() != 3) {
+			t.Errorf("orientation %d: expected 2x3, got %dx%d", orientation, b.Dx(), b.Dy())
+		}
+	}
+}
+
+func TestSanitizeImage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("metadata is stripped and the orientation is applied", func(t *testing.T) {
+		t.Parallel()
+
+		upload := withEXIF(encodeJPEG(t, testPhoto(64, 32, 7, 5), 90), binary.BigEndian, 6)
+		sanitized, err := sanitizeImage(upload)
+		if err != nil {
+			t.Fatalf("failed to sanitize image: %v", err)
+		}
+		if bytes.Contains(sanitized.Data, []byte("Exif")) || bytes.Contains(sanitized.Data, []byte("GPS")) {
+			t.Error("expected the metadata to be stripped")
+		}
+		if b := decodeImage(t, sanitized.Data).Bounds(); b.Dx() != 32 || b.Dy() != 64 {
+			t.Errorf("expected the image rotated to 32x64, got %dx%d", b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("the EXIF of the sample image is stripped", func(t *testing.T) {
+		t.Parallel()
+
+		upload, err := os.ReadFile("../images/default.jpg")
+		if err != nil {
+			t.Fatalf("failed to read image file: %v", err)
+		}
+		sanitized, err := sanitizeImage(upload)
+		if err != nil {
+			t.Fatalf("failed to sanitize image: %v", err)
+		}
+		if !bytes.Contains(upload, []byte("Exif")) || bytes.Contains(sanitized.Data, []byte("Exif")) {
+			t.Error("expected the EXIF of the upload to be stripped")
+		}
+	})
+
+	t.Run("transparency becomes white", func(t *testing.T) {
+		t.Parallel()
+
+		sanitized, err := sanitizeImage(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 8, 8))))
+		if err != nil {
+			t.Fatalf("failed to sanitize image: %v", err)
+		}
+		if r, g, b, _ := sanitized.Image.At(4, 4).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+			t.Errorf("expected a white pixel, got %d %d %d", r, g, b)
+		}
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := sanitizeImage([]byte("jpeg")); !errors.Is(err, errInvalidImage) {
+			t.Errorf("expected errInvalidImage, got %v", err)
+		}
+	})
+}
+
+func TestStoreImageE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	imageRepo := NewImageRepository(db)
+	h := &Handlers{imgDirPath: t.TempDir(), imageRepo: imageRepo}
+	photo := encodeJPEG(t, testPhoto(64, 32, 7, 5), 90)
+
+	fileName, err := h.storeImage(t.Context(), withEXIF(photo, binary.LittleEndian, 8))
+	if err != nil {
+		t.Fatalf("failed to store image: %v", err)
+	}
+	stored, err := os.ReadFile(filepath.Join(h.imgDirPath, fileName))
+	if err != nil {
+		t.Fatalf("failed to read stored image: %v", err)
+	}
+	if hash, _ := imageHash(fileName); hash != fmt.Sprintf("%x", sha256.Sum256(stored)) {
+		t.Errorf("expected the name to be the hash of the stored image, got %s", fileName)
+	}
+	info, err := imageRepo.Load(t.Context(), fileName)
+	if err != nil {
+		t.Fatalf("failed to load image: %v", err)
+	}
+	if info.Width != 32 || info.Height != 64 {
+		t.Errorf("expected the dimensions 32x64, got %dx%d", info.Width, info.Height)
+	}
+
+	// the same photo with other metadata is the same file
+	again, err := h.storeImage(t.Context(), withEXIF(photo, binary.BigEndian, 8))
+	if err != nil || again != fileName {
+		t.Errorf("expected the same file %s, got %s (%v)", fileName, again, err)
+	}
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// ImageURLModePublic serves every image to anyone at /images/{filename}.
+	ImageURLModePublic = "public"
+	// ImageURLModeSigned serves images only at the URLs signed by the API, until they expire.
+	ImageURLModeSigned = "signed"
+
+	defaultSignedImageURLTTL = time.Hour
+	// signedImageURLGranularity rounds up the expiry, so that the URL of an image stays the same
+	// for a while and the responses embedding it can be cached.
+	signedImageURLGranularity = 5 * time.Minute
+)
+
+var (
+	errImageURLExpired          = errors.New("image URL expired")
+	errImageURLInvalidSignature = errors.New("invalid image URL signature")
+)
+
+// imageURLSigner issues and verifies HMAC-signed image URLs with an expiry.
+type imageURLSigner struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func newImageURLSigner(secret string) *imageURLSigner {
+	return &imageURLSigner{secret: []byte(secret), ttl: defaultSignedImageURLTTL, now: time.Now}
+}
+
+// Sign returns the URL of the image valid for at least the TTL.
+func (s *imageURLSigner) Sign(fileName string) string {
+	expires := s.now().Add(s.ttl + signedImageURLGranularity).Truncate(signedImageURLGranularity).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", s.signature(fileName, expires))
+	return "/images/" + url.PathEscape(fileName) + "?" + q.Encode()
+}
+
+// Verify checks the expires and signature query parameters of an image URL,
+// and returns the time until it expires.
+func (s *imageURLSigner) Verify(fileName string, query url.Values) (time.Duration, error) {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return 0, errImageURLInvalidSignature
+	}
+	if !hmac.Equal([]byte(s.signature(fileName, expires)), []byte(query.Get("signature"))) {
+		return 0, errImageURLInvalidSignature
+	}
+	remaining := time.Unix(expires, 0).Sub(s.now())
+	if remaining <= 0 {
+		return 0, errImageURLExpired
+	}
+	return remaining, nil
+}
+
+func (s *imageURLSigner) signature(fileName string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(fileName + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// withImageURLs returns copies of the items with the signed image URLs.
+// It copies the items instead of modifying them, since they may be shared with other requests.
+// It returns the items as is in the public mode.
+func (s *imageURLSigner) withImageURLs(items []*Item) []*Item {
+	if s == nil {
+		return items
+	}
+	signed := make([]*Item, len(items))
+	for i, item := range items {
+		signed[i] = s.withImageURL(item)
+	}
+	return signed
+}
+
+// withImageURL is the same as withImageURLs for a single item.
+func (s *imageURLSigner) withImageURL(item *Item) *Item {
+	if s == nil || item.Image == "" {
+		return item
+	}
+	copied := *item
+	copied.ImageURL = s.Sign(item.Image)
+	return &copied
+}
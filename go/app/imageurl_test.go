@@ -0,0 +1,98 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImageURLSigner(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	signer := newImageURLSigner("secret")
+	signer.now = func() time.Time { return now }
+
+	signed, err := url.Parse(signer.Sign("abc.jpg"))
+	if err != nil {
+		t.Fatalf("failed to parse signed URL: %v", err)
+	}
+	if signed.Path != "/images/abc.jpg" {
+		t.Errorf("unexpected path: %s", signed.Path)
+	}
+
+	tampered := url.Values{}
+	for k, v := range signed.Query() {
+		tampered[k] = v
+	}
+	tampered.Set("expires", "9999999999")
+
+	cases := map[string]struct {
+		fileName string
+		query    url.Values
+		after    time.Duration
+		wantErr  error
+	}{
+		"ok: valid":              {fileName: "abc.jpg", query: signed.Query()},
+		"ng: other image":        {fileName: "def.jpg", query: signed.Query(), wantErr: errImageURLInvalidSignature},
+		"ng: tampered expiry":    {fileName: "abc.jpg", query: tampered, wantErr: errImageURLInvalidSignature},
+		"ng: missing parameters": {fileName: "abc.jpg", query: url.Values{}, wantErr: errImageURLInvalidSignature},
+		"ng: expired":            {fileName: "abc.jpg", query: signed.Query(), after: 2 * time.Hour, wantErr: errImageURLExpired},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			verifier := newImageURLSigner("secret")
+			verifier.now = func() time.Time { return now.Add(tt.after) }
+			remaining, err := verifier.Verify(tt.fileName, tt.query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && remaining < defaultSignedImageURLTTL {
+				t.Errorf("expected the URL to be valid for at least %s, got %s", defaultSignedImageURLTTL, remaining)
+			}
+		})
+	}
+}
+
+func TestGetImageSigned(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hashed := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855.jpg"
+	if err := os.WriteFile(dir+"/"+hashed, []byte("image"), 0644); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+	h := &Handlers{imgDirPath: dir, imageSigner: newImageURLSigner("secret")}
+
+	cases := map[string]struct {
+		target string
+		code   int
+	}{
+		"ok: signed URL":   {target: h.imageSigner.Sign(hashed), code: http.StatusOK},
+		"ng: unsigned URL": {target: "/images/" + hashed, code: http.StatusForbidden},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.SetPathValue("filename", hashed)
+			rr := httptest.NewRecorder()
+			h.GetImage(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("expected status code %d, got %d", tt.code, rr.Code)
+			}
+			if tt.code == http.StatusOK && !strings.HasPrefix(rr.Header().Get("Cache-Control"), "private, max-age=") {
+				t.Errorf("unexpected Cache-Control: %q", rr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
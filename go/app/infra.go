@@ -1,36 +1,356 @@
 package app
 
 import (
+	"cmp"
 	"context"
-	// "encoding/json"
 	"database/sql"
+	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// "net/http"
 
 	// STEP 5-1: uncomment this line
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
-var errImageNotFound = errors.New("image not found")
+var (
+	errImageNotFound = errors.New("image not found")
+	errItemNotFound  = errors.New("item not found")
+	errAlreadyLiked  = errors.New("item already liked")
+	errLikeNotFound  = errors.New("like not found")
+	errCommentNotFound = errors.New("comment not found")
+	errWebhookNotFound = errors.New("webhook not found")
+	errUserExists      = errors.New("user already exists")
+	errIdempotencyKeyExists = errors.New("idempotency key already exists")
+	errIdempotencyKeyNotFound = errors.New("idempotency key not found")
+)
+
+// RepositoryOption is an option of the constructors of the repositories.
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	readDB *sql.DB
+}
+
+// WithReadDB makes a repository run its read-only queries on readDB, such as DBPools.Read,
+// instead of the pool given to its constructor.
+func WithReadDB(readDB *sql.DB) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.readDB = readDB
+	}
+}
+
+// newRepositoryOptions applies opts over the defaults, which read from db.
+func newRepositoryOptions(db *sql.DB, opts []RepositoryOption) repositoryOptions {
+	o := repositoryOptions{readDB: db}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// statuses of an item. Only published items are visible to users other than the seller.
+const (
+	itemStatusDraft     = "draft"
+	itemStatusScheduled = "scheduled"
+	itemStatusPublished = "published"
+	// itemStatusPendingReview is an item flagged by the moderation rules, waiting for an admin to approve it as a draft.
+	itemStatusPendingReview = "pending_review"
+	// itemStatusRejected is an item rejected by an admin, which can never be published.
+	itemStatusRejected = "rejected"
+)
 
 type Item struct {
-	ID   int    `db:"id" json:"-"`
+	ID   int    `db:"id" json:"id"`
 	Name string `db:"name" json:"name"`
 	Category string `db:"category" json:"category"`
 	Image string `db:"image" json:"image"`
+	// ImageURL is the signed URL of the image, only set in the signed image URL mode.
+	ImageURL string `db:"-" json:"image_url,omitempty"`
+	SellerID int `db:"seller_id" json:"seller_id,omitempty"`
+	Status string `db:"status" json:"status"`
+	// PublishAt is the time the item is published, or is scheduled to be published.
+	PublishAt *time.Time `db:"publish_at" json:"publish_at,omitempty"`
+	LikeCount int `db:"like_count" json:"like_count"`
+	CommentCount int `db:"comment_count" json:"comment_count"`
+	// ModerationReasons are the reasons the moderation flagged the new item for,
+	// recorded in the same transaction as inserting it.
+	ModerationReasons []string `db:"-" json:"-"`
+}
+
+type Category struct {
+	ID   int    `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+}
+
+type User struct {
+	ID        int       `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Email     string    `db:"email" json:"email"`
+	// IsAdmin allows the user to review the listings flagged by the moderation.
+	IsAdmin   bool      `db:"is_admin" json:"is_admin"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type Webhook struct {
+	ID  int    `db:"id" json:"id"`
+	URL string `db:"url" json:"url"`
+	// Secret is the key to sign the payloads with. It is only returned when the webhook is created.
+	Secret    string    `db:"secret" json:"-"`
+	Events    []string  `db:"events" json:"events"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID         int       `db:"id" json:"id"`
+	WebhookID  int       `db:"webhook_id" json:"webhook_id"`
+	EventType  string    `db:"event_type" json:"event_type"`
+	Payload    string    `db:"payload" json:"payload"`
+	Attempt    int       `db:"attempt" json:"attempt"`
+	StatusCode int       `db:"status_code" json:"status_code,omitempty"`
+	Error      string    `db:"error" json:"error,omitempty"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// IdempotencyKey is the Idempotency-Key header of a request of the user, with the response to replay for its retries.
+type IdempotencyKey struct {
+	UserID int    `db:"user_id"`
+	Key    string `db:"idempotency_key"`
+	// RequestHash identifies the request, to detect the key reused for a different one.
+	RequestHash string `db:"request_hash"`
+	// StatusCode and Body are the response, where StatusCode is 0 while the request is in progress.
+	StatusCode int       `db:"status_code"`
+	Body       []byte    `db:"body"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// actions of a ModerationEvent
+const (
+	moderationActionFlagged  = "flagged"
+	moderationActionApproved = "approved"
+	moderationActionRejected = "rejected"
+)
+
+// ModerationEvent is an entry of the audit trail of the moderation of an item.
+type ModerationEvent struct {
+	ID     int    `db:"id" json:"id"`
+	ItemID int    `db:"item_id" json:"item_id"`
+	Action string `db:"action" json:"action"`
+	// ActorID is the admin who approved or rejected the item, or 0 for the flags by the rules.
+	ActorID int `db:"actor_id" json:"actor_id,omitempty"`
+	// Reasons are the reasons of a flag, or the note of an admin.
+	Reasons   []string  `db:"reasons" json:"reasons,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// ItemInReview is an item pending review with the reasons it was flagged for.
+type ItemInReview struct {
+	*Item
+	Reasons []string `json:"reasons"`
+}
+
+// ImageInfo is an image stored by its name.
+type ImageInfo struct {
+	Name string `db:"name" json:"name"`
+	// Hash is the perceptual hash of the image.
+	Hash uint64 `db:"hash" json:"-"`
+	// Width and Height are the dimensions of the image uploaded, after applying its EXIF orientation.
+	Width  int `db:"width" json:"width"`
+	Height int `db:"height" json:"height"`
+}
+
+// actions of an AuditEntry
+const (
+	auditActionCreate  = "create"
+	auditActionUpdate  = "update"
+	auditActionPublish = "publish"
+	auditActionFlag    = "flag"
+	auditActionApprove = "approve"
+	auditActionReject  = "reject"
+)
+
+// AuditEntry is an entry of the append-only audit log of the changes of the items.
+type AuditEntry struct {
+	ID     int `db:"id" json:"id"`
+	ItemID int `db:"item_id" json:"item_id"`
+	// ActorID is the user who made the change, or 0 for the changes by the server, such as the scheduled publishing.
+	ActorID int    `db:"actor_id" json:"actor_id,omitempty"`
+	Action  string `db:"action" json:"action"`
+	// Before and After are the item as JSON, where Before is null for a new item.
+	Before    json.RawMessage `db:"before" json:"before"`
+	After     json.RawMessage `db:"after" json:"after"`
+	RequestID string          `db:"request_id" json:"request_id,omitempty"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// AuditLogFilter narrows down the entries of LoadAuditLog. The zero values of the fields match any entry.
+type AuditLogFilter struct {
+	ItemID int
+	// Since is inclusive and Until is exclusive.
+	Since, Until  time.Time
+	Limit, Offset int
+}
+
+type Comment struct {
+	ID            int       `db:"id" json:"id"`
+	ItemID        int       `db:"item_id" json:"item_id"`
+	UserID        int       `db:"user_id" json:"user_id"`
+	Body          string    `db:"body" json:"body"`
+	IsSellerReply bool      `db:"is_seller_reply" json:"is_seller_reply"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
 }
 
 // Please run `go generate ./...` to generate the mock implementation
 // ItemRepository is an interface to manage items.
 //
 //go:generate go run go.uber.org/mock/mockgen -source=$GOFILE -package=${GOPACKAGE} -destination=./mock_$GOFILE
+//
+// The methods loading items return the published items and the items of the viewer,
+// so that the drafts are only visible to their seller. A viewerID of 0 means an anonymous viewer.
 type ItemRepository interface {
 	Insert(ctx context.Context, item *Item) error
-	LoadItems(ctx context.Context) ([]*Item, error)
-	SearchItems(ctx context.Context, keyword string) ([]*Item, error)
+	LoadItems(ctx context.Context, viewerID int) ([]*Item, error)
+	LoadItem(ctx context.Context, itemID, viewerID int) (*Item, error)
+	SearchItems(ctx context.Context, keyword string, viewerID int) ([]*Item, error)
+	// Update updates the name and category of the item of the seller.
+	Update(ctx context.Context, item *Item) error
+	// Publish publishes a draft of the seller at publishAt, immediately if it is not in the future.
+	Publish(ctx context.Context, itemID, sellerID int, publishAt time.Time) error
+	// PublishDueItems publishes the scheduled items whose time has come, and returns their IDs.
+	PublishDueItems(ctx context.Context, now time.Time) ([]int, error)
+	// InsertItems inserts the items in a single transaction: either all of them or none are inserted.
+	InsertItems(ctx context.Context, items []*Item) error
+	// ForEachItem calls fn for every item visible to the viewer in the order of their IDs,
+	// reading them one by one instead of loading all of them. It stops at the first error of fn.
+	ForEachItem(ctx context.Context, viewerID int, fn func(*Item) error) error
+	// LoadImageNames returns the names of the images referenced by any item.
+	LoadImageNames(ctx context.Context) ([]string, error)
+	// LoadCategories returns all the categories in the order of their IDs.
+	LoadCategories(ctx context.Context) ([]*Category, error)
+	// LoadItemsInCategories returns the items visible to the viewer in any of the categories,
+	// to load the items of many categories in a single query.
+	LoadItemsInCategories(ctx context.Context, categories []string, viewerID int) ([]*Item, error)
+	// Close releases the resources of the repository, such as its prepared statements.
+	Close() error
+}
+
+// UserRepository is an interface to manage users.
+type UserRepository interface {
+	Insert(ctx context.Context, user *User) error
+	// LoadUsers returns the users of the IDs. The IDs without a user are skipped.
+	LoadUsers(ctx context.Context, userIDs []int) ([]*User, error)
+}
+
+// LikeRepository is an interface to manage likes on items.
+type LikeRepository interface {
+	Like(ctx context.Context, userID, itemID int) error
+	Unlike(ctx context.Context, userID, itemID int) error
+	LoadLikedItems(ctx context.Context, userID int) ([]*Item, error)
+	// LoadLikedItemIDs returns the IDs of the items liked by the user among itemIDs.
+	LoadLikedItemIDs(ctx context.Context, userID int, itemIDs []int) ([]int, error)
+}
+
+// CommentRepository is an interface to manage comments on items.
+type CommentRepository interface {
+	Insert(ctx context.Context, comment *Comment) error
+	LoadComments(ctx context.Context, itemID, limit, offset int) ([]*Comment, error)
+	Delete(ctx context.Context, commentID, userID int) error
+}
+
+// WebhookRepository is an interface to manage webhook subscriptions and their delivery log.
+type WebhookRepository interface {
+	InsertWebhook(ctx context.Context, hook *Webhook) error
+	LoadWebhooks(ctx context.Context) ([]*Webhook, error)
+	LoadWebhooksForEvent(ctx context.Context, eventType string) ([]*Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID int) error
+	InsertDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	LoadDeliveries(ctx context.Context, webhookID int) ([]*WebhookDelivery, error)
+}
+
+// IdempotencyKeyRepository is an interface to manage the idempotency keys of the requests and their responses.
+type IdempotencyKeyRepository interface {
+	// Reserve records the key in progress, replacing the same key of the user recorded before since.
+	// If the key is recorded since then, it returns the recorded one with errIdempotencyKeyExists.
+	Reserve(ctx context.Context, key *IdempotencyKey, since time.Time) (*IdempotencyKey, error)
+	// Complete records the response of the reserved key.
+	Complete(ctx context.Context, key *IdempotencyKey) error
+	// Release removes the reserved key, to let a retry run the request again.
+	Release(ctx context.Context, userID int, key string) error
+	// Purge removes the keys recorded before the time, and returns the number of them.
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// ModerationRepository is an interface to manage the review of the items flagged by the moderation.
+type ModerationRepository interface {
+	// Flag puts the item in review for the reasons, unless it is rejected already.
+	Flag(ctx context.Context, itemID int, reasons []string) error
+	// Approve returns the item in review to its seller as a draft.
+	Approve(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error)
+	// Reject rejects the item in review.
+	Reject(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error)
+	// LoadItemsInReview returns the items in review, the oldest first.
+	LoadItemsInReview(ctx context.Context) ([]*ItemInReview, error)
+	// LoadEvents returns the audit trail of the moderation of the item, the oldest first.
+	LoadEvents(ctx context.Context, itemID int) ([]*ModerationEvent, error)
+}
+
+// AuditLogRepository is an interface to read the audit log, which is written by the repositories changing the items.
+type AuditLogRepository interface {
+	// LoadAuditLog returns the entries matching the filter, the newest first.
+	LoadAuditLog(ctx context.Context, filter AuditLogFilter) ([]*AuditEntry, error)
+}
+
+// ImageRepository is an interface to manage the stored images,
+// with their perceptual hashes which are close for the same photo even if it is resized or re-encoded.
+type ImageRepository interface {
+	// Save records the image, keeping the one recorded already.
+	Save(ctx context.Context, image *ImageInfo) error
+	// Load returns the image of the name, or errImageNotFound if it is not recorded.
+	Load(ctx context.Context, name string) (*ImageInfo, error)
+	// Delete removes the record of the image of the name, if any.
+	Delete(ctx context.Context, name string) error
+	// LoadSimilarItems returns the items visible to the viewer whose image hash is within maxDistance bits of hash,
+	// in the order of their IDs.
+	LoadSimilarItems(ctx context.Context, hash uint64, maxDistance, viewerID int) ([]*Item, error)
+}
+
+// visibleTo is the condition of the items visible to the viewer given as the parameter.
+const visibleTo = `(items.status = '` + itemStatusPublished + `' OR items.seller_id = ?)`
+
+// itemColumns is the list of columns selected for an Item. Use it with scanItems.
+const itemColumns = `items.id, items.name, categories.name, items.image,
+            COALESCE(items.seller_id, 0), items.status, items.publish_at,
+            (SELECT COUNT(*) FROM likes WHERE likes.item_id = items.id),
+            (SELECT COUNT(*) FROM comments WHERE comments.item_id = items.id)`
+
+// scanItems scans rows selected with itemColumns into items.
+func scanItems(rows *sql.Rows) ([]*Item, error) {
+	var items []*Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// scanItem scans the current row selected with itemColumns into an item.
+func scanItem(rows *sql.Rows) (*Item, error) {
+	var item Item
+	var publishAt sql.NullTime
+	if err := rows.Scan(&item.ID, &item.Name, &item.Category, &item.Image, &item.SellerID, &item.Status, &publishAt, &item.LikeCount, &item.CommentCount); err != nil {
+		return nil, err
+	}
+	if publishAt.Valid {
+		item.PublishAt = &publishAt.Time
+	}
+	return &item, nil
 }
 
 // itemRepository is an implementation of ItemRepository
@@ -38,90 +358,1171 @@ type itemRepository struct {
 	// fileName is the path to the JSON file storing items.
 	// fileName string
 	db *sql.DB
+	// readDB is the pool to read from, which can be a separate one from db.
+	readDB *sql.DB
+	stmts  itemStatements
+}
+
+// queries of itemRepository prepared as itemStatements.
+const (
+	loadItemsQuery = `
+        SELECT ` + itemColumns + `
+        FROM items 
+        JOIN categories ON items.category_id = categories.id
+        WHERE ` + visibleTo + `
+    `
+	loadItemQuery = `
+        SELECT ` + itemColumns + `
+        FROM items
+        JOIN categories ON items.category_id = categories.id
+        WHERE items.id = ? AND ` + visibleTo + `
+    `
+	searchItemsQuery = `
+        SELECT ` + itemColumns + `
+        FROM items 
+        JOIN categories ON items.category_id = categories.id
+        WHERE items.name LIKE ? AND ` + visibleTo + `
+    `
+	selectCategoryQuery = "SELECT id FROM categories WHERE name = ?"
+	insertCategoryQuery = "INSERT INTO categories (name) VALUES (?)"
+	insertItemQuery     = "INSERT INTO items (name, category_id, image, seller_id, status) VALUES (?, ?, ?, ?, ?)"
+)
+
+// itemStatements are the statements of the frequent queries, prepared once and reused across requests
+// instead of parsing their SQL on every call.
+type itemStatements struct {
+	// on readDB
+	loadItems   *sql.Stmt
+	loadItem    *sql.Stmt
+	searchItems *sql.Stmt
+	// on db
+	selectCategory *sql.Stmt
+	insertCategory *sql.Stmt
+	insertItem     *sql.Stmt
+}
+
+// NewItemRepository creates a new itemRepository and prepares its statements.
+// The schema must be migrated beforehand. Close the repository to close the statements.
+func NewItemRepository(db *sql.DB, opts ...RepositoryOption) (ItemRepository, error) {
+	o := newRepositoryOptions(db, opts)
+	i := &itemRepository{db: db, readDB: o.readDB}
+
+	for _, s := range []struct {
+		stmt  **sql.Stmt
+		db    *sql.DB
+		query string
+	}{
+		{&i.stmts.loadItems, i.readDB, loadItemsQuery},
+		{&i.stmts.loadItem, i.readDB, loadItemQuery},
+		{&i.stmts.searchItems, i.readDB, searchItemsQuery},
+		{&i.stmts.selectCategory, i.db, selectCategoryQuery},
+		{&i.stmts.insertCategory, i.db, insertCategoryQuery},
+		{&i.stmts.insertItem, i.db, insertItemQuery},
+	} {
+		stmt, err := s.db.Prepare(s.query)
+		if err != nil {
+			i.Close()
+			return nil, fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		*s.stmt = stmt
+	}
+	return i, nil
+}
+
+// Close closes the prepared statements. The pools are left open, since the repository does not own them.
+func (i *itemRepository) Close() error {
+	var errs []error
+	for _, stmt := range []*sql.Stmt{
+		i.stmts.loadItems, i.stmts.loadItem, i.stmts.searchItems,
+		i.stmts.selectCategory, i.stmts.insertCategory, i.stmts.insertItem,
+	} {
+		if stmt != nil {
+			errs = append(errs, stmt.Close())
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// NewItemRepository creates a new itemRepository.
-func NewItemRepository() ItemRepository {
-	db, err := sql.Open("sqlite3", "db/mercari.sqlite3")
+func (i *itemRepository) LoadItems(ctx context.Context, viewerID int) ([]*Item, error) {
+	rows, err := i.stmts.loadItems.QueryContext(ctx, viewerID)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return &itemRepository{db: db}
+	defer rows.Close()
+
+	return scanItems(rows)
 }
-func (i *itemRepository) LoadItems(ctx context.Context) ([]*Item, error) {
+
+// ForEachItem calls fn for every item visible to the viewer, one row at a time.
+func (i *itemRepository) ForEachItem(ctx context.Context, viewerID int, fn func(*Item) error) error {
 	query := `
-        SELECT items.id, items.name, categories.name, items.image 
-        FROM items 
+        SELECT ` + itemColumns + `
+        FROM items
         JOIN categories ON items.category_id = categories.id
+        WHERE ` + visibleTo + `
+        ORDER BY items.id
     `
-	rows, err := i.db.QueryContext(ctx, query)
+	rows, err := i.readDB.QueryContext(ctx, query, viewerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// LoadImageNames returns the names of the images referenced by any item, including drafts.
+func (i *itemRepository) LoadImageNames(ctx context.Context) ([]string, error) {
+	rows, err := i.readDB.QueryContext(ctx, "SELECT DISTINCT image FROM items")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var items []*Item
+	var names []string
 	for rows.Next() {
-		var item Item
-		if err := rows.Scan(&item.ID, &item.Name, &item.Category, &item.Image); err != nil {
+		var name string
+		if err := rows.Scan(&name); err != nil {
 			return nil, err
 		}
-		items = append(items, &item)
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// LoadCategories returns all the categories in the order of their IDs.
+func (i *itemRepository) LoadCategories(ctx context.Context) ([]*Category, error) {
+	rows, err := i.readDB.QueryContext(ctx, "SELECT id, name FROM categories ORDER BY id")
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return items, nil
+	categories := []*Category{}
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &c)
+	}
+	return categories, rows.Err()
 }
-// Insert inserts an item into the repository.
-func (i *itemRepository) Insert(ctx context.Context, item *Item) error {
+
+// LoadItemsInCategories returns the items visible to the viewer in any of the categories, in the order of their IDs.
+func (i *itemRepository) LoadItemsInCategories(ctx context.Context, categories []string, viewerID int) ([]*Item, error) {
+	if len(categories) == 0 {
+		return nil, nil
+	}
+	query := `
+        SELECT ` + itemColumns + `
+        FROM items
+        JOIN categories ON items.category_id = categories.id
+        WHERE categories.name IN (` + placeholders(len(categories)) + `) AND ` + visibleTo + `
+        ORDER BY items.id
+    `
+	args := make([]any, 0, len(categories)+1)
+	for _, c := range categories {
+		args = append(args, c)
+	}
+	rows, err := i.readDB.QueryContext(ctx, query, append(args, viewerID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanItems(rows)
+}
+
+// LoadItem loads an item visible to the viewer.
+func (i *itemRepository) LoadItem(ctx context.Context, itemID, viewerID int) (*Item, error) {
+	rows, err := i.stmts.loadItem.QueryContext(ctx, itemID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items, err := scanItems(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errItemNotFound
+	}
+	return items[0], nil
+}
+
+// inTx returns the prepared statement to run in tx, or stmt itself if tx is nil.
+func inTx(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt) *sql.Stmt {
+	if tx == nil {
+		return stmt
+	}
+	return tx.StmtContext(ctx, stmt)
+}
+
+// categoryID returns the ID of the category, creating it if it does not exist.
+// It runs in tx unless tx is nil.
+func (i *itemRepository) categoryID(ctx context.Context, tx *sql.Tx, name string) (int, error) {
 	var categoryID int
-	err := i.db.QueryRowContext(ctx, "SELECT id FROM categories WHERE name = ?", item.Category).Scan(&categoryID)
+	err := inTx(ctx, tx, i.stmts.selectCategory).QueryRowContext(ctx, name).Scan(&categoryID)
 	if err == sql.ErrNoRows {
 		// カテゴリが存在しない場合 -> 新規作成
-		res, err := i.db.ExecContext(ctx, "INSERT INTO categories (name) VALUES (?)", item.Category)
+		res, err := inTx(ctx, tx, i.stmts.insertCategory).ExecContext(ctx, name)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		lastID, err := res.LastInsertId()
 		if err != nil {
-			return err
+			return 0, err
 		}
 		categoryID = int(lastID)
 	} else if err != nil {
+		return 0, err
+	}
+	return categoryID, nil
+}
+
+// Insert inserts an item into the repository.
+func (i *itemRepository) Insert(ctx context.Context, item *Item) error {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := i.insertItem(ctx, tx, item); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// InsertItems inserts the items in a single transaction.
+func (i *itemRepository) InsertItems(ctx context.Context, items []*Item) error {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		if err := i.insertItem(ctx, tx, item); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertItem inserts an item and records it in the audit log in tx, with the flag of the moderation if it has the reasons.
+func (i *itemRepository) insertItem(ctx context.Context, tx *sql.Tx, item *Item) error {
+	categoryID, err := i.categoryID(ctx, tx, item.Category)
+	if err != nil {
 		return err
 	}
 
 	// items テーブルに新しいデータを挿入
-	_, err = i.db.ExecContext(ctx, "INSERT INTO items (name, category_id, image) VALUES (?, ?, ?)", item.Name, categoryID, item.Image)
-	return err
+	status := cmp.Or(item.Status, itemStatusPublished)
+	res, err := inTx(ctx, tx, i.stmts.insertItem).ExecContext(ctx, item.Name, categoryID, item.Image, nullInt(item.SellerID), status)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	item.ID = int(id)
+	item.Status = status
+	if len(item.ModerationReasons) > 0 {
+		if _, err := insertModerationEvent(ctx, tx, item.ID, moderationActionFlagged, 0, item.ModerationReasons); err != nil {
+			return err
+		}
+	}
+	return insertAuditEntry(ctx, tx, item.ID, auditActionCreate, nil, item)
 }
 
-// StoreImage stores an image and returns an error if any.
-// This package doesn't have a related interface for simplicity.
-func StoreImage(fileName string, image []byte) error {
-	// STEP 4-4: add an implementation to store an image
-	return os.WriteFile(fileName, image, 0644)
+// Update updates the name and category of the item of the seller.
+func (i *itemRepository) Update(ctx context.Context, item *Item) error {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := loadItemInTx(ctx, tx, item.ID)
+	if err != nil {
+		return err
+	}
+	categoryID, err := i.categoryID(ctx, tx, item.Category)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE items SET name = ?, category_id = ? WHERE id = ? AND seller_id = ?", item.Name, categoryID, item.ID, item.SellerID)
+	if err != nil {
+		return err
+	}
+	if err := expectOneRow(res, errItemNotFound); err != nil {
+		return err
+	}
+	after := *before
+	after.Name, after.Category = item.Name, item.Category
+	if err := insertAuditEntry(ctx, tx, item.ID, auditActionUpdate, before, &after); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (i *itemRepository) SearchItems(ctx context.Context, keyword string) ([]*Item, error) {
+// Publish publishes a draft or reschedules a scheduled item of the seller.
+func (i *itemRepository) Publish(ctx context.Context, itemID, sellerID int, publishAt time.Time) error {
+	status := itemStatusScheduled
+	if !publishAt.After(time.Now()) {
+		status = itemStatusPublished
+	}
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := loadItemInTx(ctx, tx, itemID)
+	if err != nil {
+		return err
+	}
 	query := `
-        SELECT items.id, items.name, categories.name, items.image 
-        FROM items 
-        JOIN categories ON items.category_id = categories.id
-        WHERE items.name LIKE ?
+        UPDATE items SET status = ?, publish_at = ?
+        WHERE id = ? AND seller_id = ? AND status IN ('` + itemStatusDraft + `', '` + itemStatusScheduled + `')
     `
-	rows, err := i.db.QueryContext(ctx, query, "%"+keyword+"%")
+	res, err := tx.ExecContext(ctx, query, status, publishAt.UTC(), itemID, sellerID)
+	if err != nil {
+		return err
+	}
+	if err := expectOneRow(res, errItemNotFound); err != nil {
+		return err
+	}
+	after := *before
+	publishAt = publishAt.UTC()
+	after.Status, after.PublishAt = status, &publishAt
+	if err := insertAuditEntry(ctx, tx, itemID, auditActionPublish, before, &after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PublishDueItems publishes the scheduled items whose time has come, and returns their IDs.
+func (i *itemRepository) PublishDueItems(ctx context.Context, now time.Time) ([]int, error) {
+	tx, err := i.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var items []*Item
-	for rows.Next() {
-		var item Item
-		if err := rows.Scan(&item.ID, &item.Name, &item.Category, &item.Image); err != nil {
+	// the items are selected in the same transaction as the update, so that they are the ones published
+	due := `items.status = '` + itemStatusScheduled + `' AND items.publish_at <= ?`
+	rows, err := tx.QueryContext(ctx, `
+        SELECT `+itemColumns+`
+        FROM items
+        JOIN categories ON items.category_id = categories.id
+        WHERE `+due+`
+        ORDER BY items.id
+    `, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	items, err := scanItems(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE items SET status = '"+itemStatusPublished+"' WHERE "+due, now.UTC()); err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(items))
+	for n, before := range items {
+		after := *before
+		after.Status = itemStatusPublished
+		if err := insertAuditEntry(ctx, tx, before.ID, auditActionPublish, before, &after); err != nil {
 			return nil, err
 		}
+		ids[n] = before.ID
+	}
+	return ids, tx.Commit()
+}
+
+// loadItemInTx loads an item of any status in tx, to record it in the audit log.
+func loadItemInTx(ctx context.Context, tx *sql.Tx, itemID int) (*Item, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT `+itemColumns+`
+        FROM items
+        JOIN categories ON items.category_id = categories.id
+        WHERE items.id = ?
+    `, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items, err := scanItems(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errItemNotFound
+	}
+	return items[0], nil
+}
+
+// insertAuditEntry records a change of the item in the audit log in tx,
+// with the actor and the request ID in ctx. before is nil for a new item.
+func insertAuditEntry(ctx context.Context, tx *sql.Tx, itemID int, action string, before, after *Item) error {
+	var beforeJSON, afterJSON []byte
+	var err error
+	if before != nil {
+		if beforeJSON, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if afterJSON, err = json.Marshal(after); err != nil {
+		return err
+	}
+	actor := auditActorFrom(ctx)
+	_, err = tx.ExecContext(ctx, "INSERT INTO audit_log (item_id, actor_id, action, before, after, request_id) VALUES (?, ?, ?, ?, ?, ?)",
+		itemID, nullInt(actor.UserID), action, nullString(beforeJSON), string(afterJSON), actor.RequestID)
+	return err
+}
+
+// nullString converts a JSON value to a nullable value, treating an empty one as NULL.
+func nullString(v []byte) sql.NullString {
+	return sql.NullString{String: string(v), Valid: len(v) > 0}
+}
+
+// expectOneRow returns errNotFound if no row was affected.
+func expectOneRow(res sql.Result, errNotFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+// placeholders returns n comma separated placeholders, for the values of an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// intArgs converts the IDs to the arguments of a query.
+func intArgs(ids []int) []any {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// nullInt converts an ID to a nullable value, treating 0 as NULL.
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
+
+// StoreImage stores an image and returns an error if any.
+// This package doesn't have a related interface for simplicity.
+func StoreImage(fileName string, image []byte) error {
+	// STEP 4-4: add an implementation to store an image
+	return os.WriteFile(fileName, image, 0644)
+}
+
+func (i *itemRepository) SearchItems(ctx context.Context, keyword string, viewerID int) ([]*Item, error) {
+	rows, err := i.stmts.searchItems.QueryContext(ctx, "%"+keyword+"%", viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanItems(rows)
+}
+
+// likeRepository is an implementation of LikeRepository
+type likeRepository struct {
+	db     *sql.DB
+	readDB *sql.DB
+}
+
+// NewLikeRepository creates a new likeRepository.
+func NewLikeRepository(db *sql.DB, opts ...RepositoryOption) LikeRepository {
+	o := newRepositoryOptions(db, opts)
+	return &likeRepository{db: db, readDB: o.readDB}
+}
+
+// Like records that the user likes the item.
+// The likes table has a UNIQUE(user_id, item_id) constraint, so a user can like an item only once.
+func (l *likeRepository) Like(ctx context.Context, userID, itemID int) error {
+	var exists int
+	err := l.db.QueryRowContext(ctx, "SELECT 1 FROM items WHERE id = ? AND status = ?", itemID, itemStatusPublished).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errItemNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = l.db.ExecContext(ctx, "INSERT INTO likes (user_id, item_id) VALUES (?, ?)", userID, itemID)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return errAlreadyLiked
+	}
+	return err
+}
+
+// Unlike removes the like of the user from the item.
+func (l *likeRepository) Unlike(ctx context.Context, userID, itemID int) error {
+	res, err := l.db.ExecContext(ctx, "DELETE FROM likes WHERE user_id = ? AND item_id = ?", userID, itemID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res, errLikeNotFound)
+}
+
+// LoadLikedItems returns the items liked by the user and still visible to them, most recently liked first.
+// An item leaves the list once it is no longer published, such as when it is put in review.
+func (l *likeRepository) LoadLikedItems(ctx context.Context, userID int) ([]*Item, error) {
+	query := `
+        SELECT ` + itemColumns + `
+        FROM items
+        JOIN likes AS liked ON liked.item_id = items.id
+        JOIN categories ON items.category_id = categories.id
+        WHERE liked.user_id = ? AND ` + visibleTo + `
+        ORDER BY liked.id DESC
+    `
+	rows, err := l.readDB.QueryContext(ctx, query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanItems(rows)
+}
+
+// LoadLikedItemIDs returns the IDs of the items liked by the user among itemIDs.
+func (l *likeRepository) LoadLikedItemIDs(ctx context.Context, userID int, itemIDs []int) ([]int, error) {
+	if len(itemIDs) == 0 {
+		return nil, nil
+	}
+	query := "SELECT item_id FROM likes WHERE user_id = ? AND item_id IN (" + placeholders(len(itemIDs)) + ")"
+	rows, err := l.readDB.QueryContext(ctx, query, append([]any{userID}, intArgs(itemIDs)...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// commentRepository is an implementation of CommentRepository
+type commentRepository struct {
+	db     *sql.DB
+	readDB *sql.DB
+}
+
+// NewCommentRepository creates a new commentRepository.
+func NewCommentRepository(db *sql.DB, opts ...RepositoryOption) CommentRepository {
+	o := newRepositoryOptions(db, opts)
+	return &commentRepository{db: db, readDB: o.readDB}
+}
+
+// Insert inserts a comment on an item.
+// The comment is flagged as a seller reply when it is posted by the seller of the item.
+func (c *commentRepository) Insert(ctx context.Context, comment *Comment) error {
+	query := `
+        INSERT INTO comments (item_id, user_id, body, is_seller_reply)
+        SELECT items.id, ?, ?, COALESCE(items.seller_id = ?, FALSE)
+        FROM items
+        WHERE items.id = ? AND items.status = ?
+        RETURNING id, is_seller_reply, created_at
+    `
+	err := c.db.QueryRowContext(ctx, query, comment.UserID, comment.Body, comment.UserID, comment.ItemID, itemStatusPublished).
+		Scan(&comment.ID, &comment.IsSellerReply, &comment.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errItemNotFound
+	}
+	return err
+}
+
+// LoadComments returns a page of comments on the item, oldest first.
+func (c *commentRepository) LoadComments(ctx context.Context, itemID, limit, offset int) ([]*Comment, error) {
+	query := `
+        SELECT id, item_id, user_id, body, is_seller_reply, created_at
+        FROM comments
+        WHERE item_id = ?
+        ORDER BY id
+        LIMIT ? OFFSET ?
+    `
+	rows, err := c.readDB.QueryContext(ctx, query, itemID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*Comment{}
+	for rows.Next() {
+		var comment Comment
+		if err := rows.Scan(&comment.ID, &comment.ItemID, &comment.UserID, &comment.Body, &comment.IsSellerReply, &comment.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, &comment)
+	}
+	return comments, rows.Err()
+}
+
+// Delete deletes a comment. Only the author of the comment can delete it.
+func (c *commentRepository) Delete(ctx context.Context, commentID, userID int) error {
+	res, err := c.db.ExecContext(ctx, "DELETE FROM comments WHERE id = ? AND user_id = ?", commentID, userID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res, errCommentNotFound)
+}
+
+// webhookRepository is an implementation of WebhookRepository
+type webhookRepository struct {
+	db     *sql.DB
+	readDB *sql.DB
+}
+
+// NewWebhookRepository creates a new webhookRepository.
+func NewWebhookRepository(db *sql.DB, opts ...RepositoryOption) WebhookRepository {
+	o := newRepositoryOptions(db, opts)
+	return &webhookRepository{db: db, readDB: o.readDB}
+}
+
+// InsertWebhook inserts a webhook subscription. The events are stored as a comma separated list.
+func (wr *webhookRepository) InsertWebhook(ctx context.Context, hook *Webhook) error {
+	query := `
+        INSERT INTO webhooks (url, secret, events)
+        VALUES (?, ?, ?)
+        RETURNING id, created_at
+    `
+	return wr.db.QueryRowContext(ctx, query, hook.URL, hook.Secret, strings.Join(hook.Events, ",")).
+		Scan(&hook.ID, &hook.CreatedAt)
+}
+
+// LoadWebhooks returns all the webhook subscriptions.
+func (wr *webhookRepository) LoadWebhooks(ctx context.Context) ([]*Webhook, error) {
+	rows, err := wr.readDB.QueryContext(ctx, "SELECT id, url, secret, events, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhooks(rows)
+}
+
+// LoadWebhooksForEvent returns the webhook subscriptions subscribing to the event type.
+func (wr *webhookRepository) LoadWebhooksForEvent(ctx context.Context, eventType string) ([]*Webhook, error) {
+	query := `
+        SELECT id, url, secret, events, created_at
+        FROM webhooks
+        WHERE ',' || events || ',' LIKE '%,' || ? || ',%'
+        ORDER BY id
+    `
+	rows, err := wr.readDB.QueryContext(ctx, query, eventType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhooks(rows)
+}
+
+func scanWebhooks(rows *sql.Rows) ([]*Webhook, error) {
+	hooks := []*Webhook{}
+	for rows.Next() {
+		var hook Webhook
+		var events string
+		if err := rows.Scan(&hook.ID, &hook.URL, &hook.Secret, &events, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		hook.Events = strings.Split(events, ",")
+		hooks = append(hooks, &hook)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhook deletes a webhook subscription.
+func (wr *webhookRepository) DeleteWebhook(ctx context.Context, webhookID int) error {
+	res, err := wr.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", webhookID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res, errWebhookNotFound)
+}
+
+// InsertDelivery records an attempt to deliver an event to a webhook.
+func (wr *webhookRepository) InsertDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	query := `
+        INSERT INTO webhook_deliveries (webhook_id, event_type, payload, attempt, status_code, error)
+        VALUES (?, ?, ?, ?, ?, ?)
+        RETURNING id, created_at
+    `
+	return wr.db.QueryRowContext(ctx, query, delivery.WebhookID, delivery.EventType, delivery.Payload,
+		delivery.Attempt, delivery.StatusCode, delivery.Error).Scan(&delivery.ID, &delivery.CreatedAt)
+}
+
+// LoadDeliveries returns the delivery log of a webhook, newest first.
+func (wr *webhookRepository) LoadDeliveries(ctx context.Context, webhookID int) ([]*WebhookDelivery, error) {
+	query := `
+        SELECT id, webhook_id, event_type, payload, attempt, status_code, error, created_at
+        FROM webhook_deliveries
+        WHERE webhook_id = ?
+        ORDER BY id DESC
+    `
+	rows, err := wr.readDB.QueryContext(ctx, query, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &d.Payload, &d.Attempt, &d.StatusCode, &d.Error, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+// userRepository is an implementation of UserRepository
+type userRepository struct {
+	db     *sql.DB
+	readDB *sql.DB
+}
+
+// NewUserRepository creates a new userRepository.
+func NewUserRepository(db *sql.DB, opts ...RepositoryOption) UserRepository {
+	o := newRepositoryOptions(db, opts)
+	return &userRepository{db: db, readDB: o.readDB}
+}
+
+// Insert inserts a user. The email must not be used by another user.
+func (u *userRepository) Insert(ctx context.Context, user *User) error {
+	err := u.db.QueryRowContext(ctx, "INSERT INTO users (name, email, is_admin) VALUES (?, ?, ?) RETURNING id, created_at", user.Name, user.Email, user.IsAdmin).
+		Scan(&user.ID, &user.CreatedAt)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return errUserExists
+	}
+	return err
+}
+
+// LoadUsers returns the users of the IDs in the order of their IDs.
+func (u *userRepository) LoadUsers(ctx context.Context, userIDs []int) ([]*User, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	query := "SELECT id, name, email, is_admin, created_at FROM users WHERE id IN (" + placeholders(len(userIDs)) + ") ORDER BY id"
+	rows, err := u.readDB.QueryContext(ctx, query, intArgs(userIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.IsAdmin, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+// idempotencyKeyRepository is an implementation of IdempotencyKeyRepository
+type idempotencyKeyRepository struct {
+	db *sql.DB
+}
+
+// NewIdempotencyKeyRepository creates a new idempotencyKeyRepository.
+// It has no read-only queries, since a retry must see the key reserved by the first request.
+func NewIdempotencyKeyRepository(db *sql.DB) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: db}
+}
+
+// Reserve records the key in progress in a transaction, so that concurrent retries cannot reserve the same key.
+func (k *idempotencyKeyRepository) Reserve(ctx context.Context, key *IdempotencyKey, since time.Time) (*IdempotencyKey, error) {
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND created_at < ?",
+		key.UserID, key.Key, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, `
+        INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, created_at) VALUES (?, ?, ?, ?)
+        ON CONFLICT (user_id, idempotency_key) DO NOTHING
+    `, key.UserID, key.Key, key.RequestHash, key.CreatedAt.UTC())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		existing := IdempotencyKey{UserID: key.UserID, Key: key.Key}
+		err := tx.QueryRowContext(ctx, "SELECT request_hash, status_code, body, created_at FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?",
+			key.UserID, key.Key).Scan(&existing.RequestHash, &existing.StatusCode, &existing.Body, &existing.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		return &existing, errIdempotencyKeyExists
+	}
+	return nil, tx.Commit()
+}
+
+func (k *idempotencyKeyRepository) Complete(ctx context.Context, key *IdempotencyKey) error {
+	res, err := k.db.ExecContext(ctx, "UPDATE idempotency_keys SET status_code = ?, body = ? WHERE user_id = ? AND idempotency_key = ?",
+		key.StatusCode, key.Body, key.UserID, key.Key)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res, errIdempotencyKeyNotFound)
+}
+
+func (k *idempotencyKeyRepository) Release(ctx context.Context, userID int, key string) error {
+	_, err := k.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND status_code = 0", userID, key)
+	return err
+}
+
+func (k *idempotencyKeyRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	res, err := k.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE created_at < ?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// moderationRepository is an implementation of ModerationRepository
+type moderationRepository struct {
+	db     *sql.DB
+	readDB *sql.DB
+}
+
+// NewModerationRepository creates a new moderationRepository.
+func NewModerationRepository(db *sql.DB, opts ...RepositoryOption) ModerationRepository {
+	o := newRepositoryOptions(db, opts)
+	return &moderationRepository{db: db, readDB: o.readDB}
+}
+
+// Flag updates the status of the item and records the flag in a single transaction.
+// The audit log records the change of the status, which is none for a rejected item.
+func (m *moderationRepository) Flag(ctx context.Context, itemID int, reasons []string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := loadItemInTx(ctx, tx, itemID)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE items SET status = ? WHERE id = ? AND status != ?", itemStatusPendingReview, itemID, itemStatusRejected)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		after := *before
+		after.Status = itemStatusPendingReview
+		if err := insertAuditEntry(ctx, tx, itemID, auditActionFlag, before, &after); err != nil {
+			return err
+		}
+	}
+	if _, err := insertModerationEvent(ctx, tx, itemID, moderationActionFlagged, 0, reasons); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *moderationRepository) Approve(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error) {
+	return m.review(ctx, itemID, adminID, itemStatusDraft, moderationActionApproved, auditActionApprove, note)
+}
+
+func (m *moderationRepository) Reject(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error) {
+	return m.review(ctx, itemID, adminID, itemStatusRejected, moderationActionRejected, auditActionReject, note)
+}
+
+// review updates the status of the item in review and records the decision of the admin in a single transaction.
+// It returns errItemNotFound if the item is not in review.
+func (m *moderationRepository) review(ctx context.Context, itemID, adminID int, status, action, auditAction, note string) (*ModerationEvent, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := loadItemInTx(ctx, tx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE items SET status = ? WHERE id = ? AND status = ?", status, itemID, itemStatusPendingReview)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectOneRow(res, errItemNotFound); err != nil {
+		return nil, err
+	}
+	after := *before
+	after.Status = status
+	// the admin is the actor, even if the request was not made through the HTTP API
+	actor := auditActorFrom(ctx)
+	actor.UserID = adminID
+	if err := insertAuditEntry(withAuditActor(ctx, actor), tx, itemID, auditAction, before, &after); err != nil {
+		return nil, err
+	}
+	var reasons []string
+	if note != "" {
+		reasons = []string{note}
+	}
+	event, err := insertModerationEvent(ctx, tx, itemID, action, adminID, reasons)
+	if err != nil {
+		return nil, err
+	}
+	return event, tx.Commit()
+}
+
+// insertModerationEvent records an entry of the audit trail in tx.
+// The reasons are stored one per line, since they can contain commas.
+func insertModerationEvent(ctx context.Context, tx *sql.Tx, itemID int, action string, actorID int, reasons []string) (*ModerationEvent, error) {
+	event := &ModerationEvent{ItemID: itemID, Action: action, ActorID: actorID, Reasons: reasons}
+	err := tx.QueryRowContext(ctx, "INSERT INTO moderation_events (item_id, action, actor_id, reasons) VALUES (?, ?, ?, ?) RETURNING id, created_at",
+		itemID, action, nullInt(actorID), strings.Join(reasons, "\n")).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// splitReasons reverts the reasons joined by insertModerationEvent.
+func splitReasons(reasons string) []string {
+	if reasons == "" {
+		return nil
+	}
+	return strings.Split(reasons, "\n")
+}
+
+// LoadItemsInReview returns the items in review with the reasons of their latest flag.
+func (m *moderationRepository) LoadItemsInReview(ctx context.Context) ([]*ItemInReview, error) {
+	query := `
+        SELECT ` + itemColumns + `,
+            COALESCE((SELECT reasons FROM moderation_events
+                WHERE moderation_events.item_id = items.id AND action = ?
+                ORDER BY moderation_events.id DESC LIMIT 1), '')
+        FROM items
+        JOIN categories ON items.category_id = categories.id
+        WHERE items.status = ?
+        ORDER BY items.id
+    `
+	rows, err := m.readDB.QueryContext(ctx, query, moderationActionFlagged, itemStatusPendingReview)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*ItemInReview{}
+	for rows.Next() {
+		var item Item
+		var publishAt sql.NullTime
+		var reasons string
+		if err := rows.Scan(&item.ID, &item.Name, &item.Category, &item.Image, &item.SellerID, &item.Status, &publishAt, &item.LikeCount, &item.CommentCount, &reasons); err != nil {
+			return nil, err
+		}
+		if publishAt.Valid {
+			item.PublishAt = &publishAt.Time
+		}
+		items = append(items, &ItemInReview{Item: &item, Reasons: splitReasons(reasons)})
+	}
+	return items, rows.Err()
+}
+
+func (m *moderationRepository) LoadEvents(ctx context.Context, itemID int) ([]*ModerationEvent, error) {
+	rows, err := m.readDB.QueryContext(ctx, "SELECT id, item_id, action, COALESCE(actor_id, 0), reasons, created_at FROM moderation_events WHERE item_id = ? ORDER BY id", itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*ModerationEvent{}
+	for rows.Next() {
+		var e ModerationEvent
+		var reasons string
+		if err := rows.Scan(&e.ID, &e.ItemID, &e.Action, &e.ActorID, &reasons, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Reasons = splitReasons(reasons)
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// auditLogRepository is an implementation of AuditLogRepository
+type auditLogRepository struct {
+	readDB *sql.DB
+}
+
+// NewAuditLogRepository creates a new auditLogRepository.
+func NewAuditLogRepository(db *sql.DB, opts ...RepositoryOption) AuditLogRepository {
+	o := newRepositoryOptions(db, opts)
+	return &auditLogRepository{readDB: o.readDB}
+}
+
+func (a *auditLogRepository) LoadAuditLog(ctx context.Context, filter AuditLogFilter) ([]*AuditEntry, error) {
+	var conds []string
+	var args []any
+	if filter.ItemID != 0 {
+		conds = append(conds, "item_id = ?")
+		args = append(args, filter.ItemID)
+	}
+	// created_at is in the format of CURRENT_TIMESTAMP, which compares as a string in UTC
+	if !filter.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, filter.Since.UTC().Format(time.DateTime))
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, filter.Until.UTC().Format(time.DateTime))
+	}
+	query := "SELECT id, item_id, COALESCE(actor_id, 0), action, before, after, request_id, created_at FROM audit_log"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit, filter.Offset)
+
+	rows, err := a.readDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var before sql.NullString
+		var after string
+		if err := rows.Scan(&e.ID, &e.ItemID, &e.ActorID, &e.Action, &before, &after, &e.RequestID, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		e.After = json.RawMessage(after)
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+// imageRepository is an implementation of ImageRepository
+type imageRepository struct {
+	db     *sql.DB
+	readDB *sql.DB
+}
+
+// NewImageRepository creates a new imageRepository.
+func NewImageRepository(db *sql.DB, opts ...RepositoryOption) ImageRepository {
+	o := newRepositoryOptions(db, opts)
+	return &imageRepository{db: db, readDB: o.readDB}
+}
+
+// Save stores the hash as a signed integer, which is the type of the integers of SQLite.
+func (im *imageRepository) Save(ctx context.Context, image *ImageInfo) error {
+	_, err := im.db.ExecContext(ctx, "INSERT INTO images (name, hash, width, height) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING",
+		image.Name, int64(image.Hash), image.Width, image.Height)
+	return err
+}
+
+func (im *imageRepository) Delete(ctx context.Context, name string) error {
+	_, err := im.db.ExecContext(ctx, "DELETE FROM images WHERE name = ?", name)
+	return err
+}
+
+func (im *imageRepository) Load(ctx context.Context, name string) (*ImageInfo, error) {
+	image := &ImageInfo{Name: name}
+	var hash int64
+	err := im.readDB.QueryRowContext(ctx, "SELECT hash, width, height FROM images WHERE name = ?", name).Scan(&hash, &image.Width, &image.Height)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	image.Hash = uint64(hash)
+	return image, nil
+}
+
+// LoadSimilarItems compares the hashes of all the visible items, since SQLite cannot count the differing bits.
+func (im *imageRepository) LoadSimilarItems(ctx context.Context, hash uint64, maxDistance, viewerID int) ([]*Item, error) {
+	query := `
+        SELECT ` + itemColumns + `, images.hash
+        FROM items
+        JOIN categories ON items.category_id = categories.id
+        JOIN images ON images.name = items.image
+        WHERE ` + visibleTo + `
+        ORDER BY items.id
+    `
+	rows, err := im.readDB.QueryContext(ctx, query, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*Item{}
+	for rows.Next() {
+		var item Item
+		var publishAt sql.NullTime
+		var itemHash int64
+		if err := rows.Scan(&item.ID, &item.Name, &item.Category, &item.Image, &item.SellerID, &item.Status, &publishAt, &item.LikeCount, &item.CommentCount, &itemHash); err != nil {
+			return nil, err
+		}
+		if hashDistance(hash, uint64(itemHash)) > maxDistance {
+			continue
+		}
+		if publishAt.Valid {
+			item.PublishAt = &publishAt.Time
+		}
 		items = append(items, &item)
 	}
-	return items, nil
+	return items, rows.Err()
 }
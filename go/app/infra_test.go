@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// unpreparedItemRepository runs the queries of LoadItems and SearchItems without the prepared statements,
+// as the baseline of the benchmarks.
+type unpreparedItemRepository struct {
+	ItemRepository
+	db *sql.DB
+}
+
+func (u unpreparedItemRepository) LoadItems(ctx context.Context, viewerID int) ([]*Item, error) {
+	rows, err := u.db.QueryContext(ctx, loadItemsQuery, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanItems(rows)
+}
+
+func (u unpreparedItemRepository) SearchItems(ctx context.Context, keyword string, viewerID int) ([]*Item, error) {
+	rows, err := u.db.QueryContext(ctx, searchItemsQuery, "%"+keyword+"%", viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanItems(rows)
+}
+
+// benchmarkItemRepositories returns the repositories to compare, on a database of 100 items.
+func benchmarkItemRepositories(b *testing.B) map[string]ItemRepository {
+	b.Helper()
+
+	pools := openTestDBPools(b, DBConfig{})
+	repo := newTestItemRepository(b, pools.Write, WithReadDB(pools.Read))
+	items := make([]*Item, 100)
+	for i := range items {
+		items[i] = &Item{Name: fmt.Sprintf("jacket%d", i), Category: fmt.Sprintf("category%d", i%5), Image: "default.jpg"}
+	}
+	if err := repo.InsertItems(b.Context(), items); err != nil {
+		b.Fatalf("failed to insert items: %v", err)
+	}
+
+	return map[string]ItemRepository{
+		"prepared":   repo,
+		"unprepared": unpreparedItemRepository{ItemRepository: repo, db: pools.Read},
+	}
+}
+
+// benchmarkHandler runs the requests to target on the handler of every repository in parallel.
+func benchmarkHandler(b *testing.B, target string, handler func(h *Handlers) http.HandlerFunc) {
+	for name, repo := range benchmarkItemRepositories(b) {
+		b.Run(name, func(b *testing.B) {
+			h := handler(&Handlers{itemRepo: repo})
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					rr := httptest.NewRecorder()
+					h(rr, httptest.NewRequest(http.MethodGet, target, nil))
+					if rr.Code != http.StatusOK {
+						b.Errorf("unexpected status code %d", rr.Code)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGetItems(b *testing.B) {
+	benchmarkHandler(b, "/items", func(h *Handlers) http.HandlerFunc { return h.GetItems })
+}
+
+func BenchmarkSearch(b *testing.B) {
+	benchmarkHandler(b, "/search?keyword=jacket1", func(h *Handlers) http.HandlerFunc { return h.Search })
+}
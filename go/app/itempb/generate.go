@@ -0,0 +1,4 @@
+// Package itempb is the protobuf definition of the gRPC API of the items, and the code generated from it.
+package itempb
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative app/itempb/items.proto
@@ -0,0 +1,603 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: app/itempb/items.proto
+
+// The gRPC API of the items, mirroring the HTTP API.
+// The user making a request is sent in the "x-user-id" metadata, like the X-User-ID header.
+
+package itempb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Item struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name     string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Category string                 `protobuf:"bytes,3,opt,name=category,proto3" json:"category,omitempty"`
+	// image is the file name of the image, and image_url is its signed URL in the signed mode.
+	Image         string                 `protobuf:"bytes,4,opt,name=image,proto3" json:"image,omitempty"`
+	ImageUrl      string                 `protobuf:"bytes,5,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"`
+	SellerId      int64                  `protobuf:"varint,6,opt,name=seller_id,json=sellerId,proto3" json:"seller_id,omitempty"`
+	Status        string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	PublishAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=publish_at,json=publishAt,proto3" json:"publish_at,omitempty"`
+	LikeCount     int64                  `protobuf:"varint,9,opt,name=like_count,json=likeCount,proto3" json:"like_count,omitempty"`
+	CommentCount  int64                  `protobuf:"varint,10,opt,name=comment_count,json=commentCount,proto3" json:"comment_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Item) Reset() {
+	*x = Item{}
+	mi := &file_app_itempb_items_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Item) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Item) ProtoMessage() {}
+
+func (x *Item) ProtoReflect() protoreflect.Message {
+	mi := &file_app_itempb_items_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Item.ProtoReflect.Descriptor instead.
+func (*Item) Descriptor() ([]byte, []int) {
+	return file_app_itempb_items_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Item) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Item) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Item) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Item) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *Item) GetImageUrl() string {
+	if x != nil {
+		return x.ImageUrl
+	}
+	return ""
+}
+
+func (x *Item) GetSellerId() int64 {
+	if x != nil {
+		return x.SellerId
+	}
+	return 0
+}
+
+func (x *Item) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Item) GetPublishAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishAt
+	}
+	return nil
+}
+
+func (x *Item) GetLikeCount() int64 {
+	if x != nil {
+		return x.LikeCount
+	}
+	return 0
+}
+
+func (x *Item) GetCommentCount() int64 {
+	if x != nil {
+		return x.CommentCount
+	}
+	return 0
+}
+
+type AddItemRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*AddItemRequest_Item
+	//	*AddItemRequest_ImageChunk
+	Data          isAddItemRequest_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddItemRequest) Reset() {
+	*x = AddItemRequest{}
+	mi := &file_app_itempb_items_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddItemRequest) ProtoMessage() {}
+
+func (x *AddItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_app_itempb_items_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddItemRequest.ProtoReflect.Descriptor instead.
+func (*AddItemRequest) Descriptor() ([]byte, []int) {
+	return file_app_itempb_items_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AddItemRequest) GetData() isAddItemRequest_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *AddItemRequest) GetItem() *AddItemRequest_NewItem {
+	if x != nil {
+		if x, ok := x.Data.(*AddItemRequest_Item); ok {
+			return x.Item
+		}
+	}
+	return nil
+}
+
+func (x *AddItemRequest) GetImageChunk() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*AddItemRequest_ImageChunk); ok {
+			return x.ImageChunk
+		}
+	}
+	return nil
+}
+
+type isAddItemRequest_Data interface {
+	isAddItemRequest_Data()
+}
+
+type AddItemRequest_Item struct {
+	Item *AddItemRequest_NewItem `protobuf:"bytes,1,opt,name=item,proto3,oneof"`
+}
+
+type AddItemRequest_ImageChunk struct {
+	ImageChunk []byte `protobuf:"bytes,2,opt,name=image_chunk,json=imageChunk,proto3,oneof"`
+}
+
+func (*AddItemRequest_Item) isAddItemRequest_Data() {}
+
+func (*AddItemRequest_ImageChunk) isAddItemRequest_Data() {}
+
+type GetItemsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetItemsRequest) Reset() {
+	*x = GetItemsRequest{}
+	mi := &file_app_itempb_items_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetItemsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetItemsRequest) ProtoMessage() {}
+
+func (x *GetItemsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_app_itempb_items_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetItemsRequest.ProtoReflect.Descriptor instead.
+func (*GetItemsRequest) Descriptor() ([]byte, []int) {
+	return file_app_itempb_items_proto_rawDescGZIP(), []int{2}
+}
+
+type GetItemsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Item                `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetItemsResponse) Reset() {
+	*x = GetItemsResponse{}
+	mi := &file_app_itempb_items_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetItemsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetItemsResponse) ProtoMessage() {}
+
+func (x *GetItemsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_app_itempb_items_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetItemsResponse.ProtoReflect.Descriptor instead.
+func (*GetItemsResponse) Descriptor() ([]byte, []int) {
+	return file_app_itempb_items_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetItemsResponse) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type GetItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetItemRequest) Reset() {
+	*x = GetItemRequest{}
+	mi := &file_app_itempb_items_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetItemRequest) ProtoMessage() {}
+
+func (x *GetItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_app_itempb_items_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetItemRequest.ProtoReflect.Descriptor instead.
+func (*GetItemRequest) Descriptor() ([]byte, []int) {
+	return file_app_itempb_items_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetItemRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type SearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keyword       string                 `protobuf:"bytes,1,opt,name=keyword,proto3" json:"keyword,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_app_itempb_items_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_app_itempb_items_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_app_itempb_items_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SearchRequest) GetKeyword() string {
+	if x != nil {
+		return x.Keyword
+	}
+	return ""
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Item                `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_app_itempb_items_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_app_itempb_items_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_app_itempb_items_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SearchResponse) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type AddItemRequest_NewItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Category      string                 `protobuf:"bytes,2,opt,name=category,proto3" json:"category,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddItemRequest_NewItem) Reset() {
+	*x = AddItemRequest_NewItem{}
+	mi := &file_app_itempb_items_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddItemRequest_NewItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddItemRequest_NewItem) ProtoMessage() {}
+
+func (x *AddItemRequest_NewItem) ProtoReflect() protoreflect.Message {
+	mi := &file_app_itempb_items_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddItemRequest_NewItem.ProtoReflect.Descriptor instead.
+func (*AddItemRequest_NewItem) Descriptor() ([]byte, []int) {
+	return file_app_itempb_items_proto_rawDescGZIP(), []int{1, 0}
+}
+
+func (x *AddItemRequest_NewItem) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AddItemRequest_NewItem) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+var File_app_itempb_items_proto protoreflect.FileDescriptor
+
+const file_app_itempb_items_proto_rawDesc = "" +
+	"\n" +
+	"\x16app/itempb/items.proto\x12\x10mercari.items.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xad\x02\n" +
+	"\x04Item\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\bcategory\x18\x03 \x01(\tR\bcategory\x12\x14\n" +
+	"\x05image\x18\x04 \x01(\tR\x05image\x12\x1b\n" +
+	"\timage_url\x18\x05 \x01(\tR\bimageUrl\x12\x1b\n" +
+	"\tseller_id\x18\x06 \x01(\x03R\bsellerId\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"publish_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tpublishAt\x12\x1d\n" +
+	"\n" +
+	"like_count\x18\t \x01(\x03R\tlikeCount\x12#\n" +
+	"\rcomment_count\x18\n" +
+	" \x01(\x03R\fcommentCount\"\xb6\x01\n" +
+	"\x0eAddItemRequest\x12>\n" +
+	"\x04item\x18\x01 \x01(\v2(.mercari.items.v1.AddItemRequest.NewItemH\x00R\x04item\x12!\n" +
+	"\vimage_chunk\x18\x02 \x01(\fH\x00R\n" +
+	"imageChunk\x1a9\n" +
+	"\aNewItem\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bcategory\x18\x02 \x01(\tR\bcategoryB\x06\n" +
+	"\x04data\"\x11\n" +
+	"\x0fGetItemsRequest\"@\n" +
+	"\x10GetItemsResponse\x12,\n" +
+	"\x05items\x18\x01 \x03(\v2\x16.mercari.items.v1.ItemR\x05items\" \n" +
+	"\x0eGetItemRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\")\n" +
+	"\rSearchRequest\x12\x18\n" +
+	"\akeyword\x18\x01 \x01(\tR\akeyword\">\n" +
+	"\x0eSearchResponse\x12,\n" +
+	"\x05items\x18\x01 \x03(\v2\x16.mercari.items.v1.ItemR\x05items2\xb9\x02\n" +
+	"\vItemService\x12E\n" +
+	"\aAddItem\x12 .mercari.items.v1.AddItemRequest\x1a\x16.mercari.items.v1.Item(\x01\x12Q\n" +
+	"\bGetItems\x12!.mercari.items.v1.GetItemsRequest\x1a\".mercari.items.v1.GetItemsResponse\x12C\n" +
+	"\aGetItem\x12 .mercari.items.v1.GetItemRequest\x1a\x16.mercari.items.v1.Item\x12K\n" +
+	"\x06Search\x12\x1f.mercari.items.v1.SearchRequest\x1a .mercari.items.v1.SearchResponseB#Z!mercari-build-training/app/itempbb\x06proto3"
+
+var (
+	file_app_itempb_items_proto_rawDescOnce sync.Once
+	file_app_itempb_items_proto_rawDescData []byte
+)
+
+func file_app_itempb_items_proto_rawDescGZIP() []byte {
+	file_app_itempb_items_proto_rawDescOnce.Do(func() {
+		file_app_itempb_items_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_app_itempb_items_proto_rawDesc), len(file_app_itempb_items_proto_rawDesc)))
+	})
+	return file_app_itempb_items_proto_rawDescData
+}
+
+var file_app_itempb_items_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_app_itempb_items_proto_goTypes = []any{
+	(*Item)(nil),                   // 0: mercari.items.v1.Item
+	(*AddItemRequest)(nil),         // 1: mercari.items.v1.AddItemRequest
+	(*GetItemsRequest)(nil),        // 2: mercari.items.v1.GetItemsRequest
+	(*GetItemsResponse)(nil),       // 3: mercari.items.v1.GetItemsResponse
+	(*GetItemRequest)(nil),         // 4: mercari.items.v1.GetItemRequest
+	(*SearchRequest)(nil),          // 5: mercari.items.v1.SearchRequest
+	(*SearchResponse)(nil),         // 6: mercari.items.v1.SearchResponse
+	(*AddItemRequest_NewItem)(nil), // 7: mercari.items.v1.AddItemRequest.NewItem
+	(*timestamppb.Timestamp)(nil),  // 8: google.protobuf.Timestamp
+}
+var file_app_itempb_items_proto_depIdxs = []int32{
+	8, // 0: mercari.items.v1.Item.publish_at:type_name -> google.protobuf.Timestamp
+	7, // 1: mercari.items.v1.AddItemRequest.item:type_name -> mercari.items.v1.AddItemRequest.NewItem
+	0, // 2: mercari.items.v1.GetItemsResponse.items:type_name -> mercari.items.v1.Item
+	0, // 3: mercari.items.v1.SearchResponse.items:type_name -> mercari.items.v1.Item
+	1, // 4: mercari.items.v1.ItemService.AddItem:input_type -> mercari.items.v1.AddItemRequest
+	2, // 5: mercari.items.v1.ItemService.GetItems:input_type -> mercari.items.v1.GetItemsRequest
+	4, // 6: mercari.items.v1.ItemService.GetItem:input_type -> mercari.items.v1.GetItemRequest
+	5, // 7: mercari.items.v1.ItemService.Search:input_type -> mercari.items.v1.SearchRequest
+	0, // 8: mercari.items.v1.ItemService.AddItem:output_type -> mercari.items.v1.Item
+	3, // 9: mercari.items.v1.ItemService.GetItems:output_type -> mercari.items.v1.GetItemsResponse
+	0, // 10: mercari.items.v1.ItemService.GetItem:output_type -> mercari.items.v1.Item
+	6, // 11: mercari.items.v1.ItemService.Search:output_type -> mercari.items.v1.SearchResponse
+	8, // [8:12] is the sub-list for method output_type
+	4, // [4:8] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_app_itempb_items_proto_init() }
+func file_app_itempb_items_proto_init() {
+	if File_app_itempb_items_proto != nil {
+		return
+	}
+	file_app_itempb_items_proto_msgTypes[1].OneofWrappers = []any{
+		(*AddItemRequest_Item)(nil),
+		(*AddItemRequest_ImageChunk)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_app_itempb_items_proto_rawDesc), len(file_app_itempb_items_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_app_itempb_items_proto_goTypes,
+		DependencyIndexes: file_app_itempb_items_proto_depIdxs,
+		MessageInfos:      file_app_itempb_items_proto_msgTypes,
+	}.Build()
+	File_app_itempb_items_proto = out.File
+	file_app_itempb_items_proto_goTypes = nil
+	file_app_itempb_items_proto_depIdxs = nil
+}
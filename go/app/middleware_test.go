@@ -0,0 +1,133 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux.HandleFunc("GET /items/{item_id}", ok)
+	mux.HandleFunc("POST /items/{item_id}/like", ok)
+	mux.HandleFunc("DELETE /items/{item_id}/like", ok)
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"http://localhost:3000", "https://*.example.com"},
+		AllowedHeaders:   []string{"Content-Type"},
+		ExposedHeaders:   []string{"Retry-After"},
+		AllowCredentials: true,
+		MaxAge:           time.Minute,
+	}
+	h := corsMiddleware(mux, mux, cfg)
+
+	type wants struct {
+		code    int
+		headers map[string]string
+	}
+	cases := map[string]struct {
+		method  string
+		path    string
+		headers map[string]string
+		wants
+	}{
+		"ok: simple request": {
+			method:  "GET",
+			path:    "/items/1",
+			headers: map[string]string{"Origin": "http://localhost:3000"},
+			wants: wants{
+				code: http.StatusOK,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin":      "http://localhost:3000",
+					"Access-Control-Allow-Credentials": "true",
+					"Access-Control-Expose-Headers":    "Retry-After",
+					"Access-Control-Allow-Methods":     "",
+					"Vary":                             "Origin",
+				},
+			},
+		},
+		"ok: preflight with a pattern origin": {
+			method: "OPTIONS",
+			path:   "/items/1/like",
+			headers: map[string]string{
+				"Origin":                        "https://shop.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			wants: wants{
+				code: http.StatusNoContent,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin":  "https://shop.example.com",
+					"Access-Control-Allow-Methods": "POST, DELETE",
+					"Access-Control-Allow-Headers": "Content-Type",
+					"Access-Control-Max-Age":       "60",
+				},
+			},
+		},
+		"ok: OPTIONS without preflight is passed through": {
+			method:  "OPTIONS",
+			path:    "/items/1",
+			headers: map[string]string{"Origin": "http://localhost:3000"},
+			wants: wants{
+				code: http.StatusMethodNotAllowed,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin": "http://localhost:3000",
+				},
+			},
+		},
+		"ng: origin not allowed": {
+			method:  "GET",
+			path:    "/items/1",
+			headers: map[string]string{"Origin": "https://evil.com"},
+			wants: wants{
+				code: http.StatusOK,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin": "",
+					"Vary":                        "Origin",
+				},
+			},
+		},
+		"ng: preflight from origin not allowed": {
+			method: "OPTIONS",
+			path:   "/items/1",
+			headers: map[string]string{
+				"Origin":                        "https://evil.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wants: wants{
+				code: http.StatusForbidden,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin": "",
+				},
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if tt.wants.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.wants.code, rr.Code)
+			}
+			got := map[string]string{}
+			for k := range tt.wants.headers {
+				got[k] = rr.Header().Get(k)
+			}
+			if diff := cmp.Diff(tt.wants.headers, got); diff != "" {
+				t.Errorf("unexpected headers (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// migrations are the SQL files changing the schema, applied in the order of their names.
+// Never edit a migration once it is merged; add a new one instead.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrate applies the migrations not applied yet to db, each in its own transaction,
+// and returns the names of the applied ones.
+func migrate(ctx context.Context, db *sql.DB) ([]string, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version TEXT PRIMARY KEY,
+        applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    )`)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := migrationNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	for _, name := range names {
+		version := strings.TrimSuffix(path.Base(name), ".sql")
+		ok, err := applyMigration(ctx, db, version, name)
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+		if ok {
+			applied = append(applied, version)
+		}
+	}
+	return applied, nil
+}
+
+// migrationNames returns the paths of the migrations in the order to apply them.
+func migrationNames() ([]string, error) {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// latestMigration returns the version of the last migration, which is the schema version this server knows.
+func latestMigration() (string, error) {
+	names, err := migrationNames()
+	if err != nil || len(names) == 0 {
+		return "", err
+	}
+	return strings.TrimSuffix(path.Base(names[len(names)-1]), ".sql"), nil
+}
+
+// applyMigration applies a migration unless it has been applied already, and reports whether it was applied.
+func applyMigration(ctx context.Context, db *sql.DB, version, name string) (bool, error) {
+	script, err := migrations.ReadFile(name)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", version).Scan(&exists)
+	if err != nil || exists > 0 {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES (?)", version); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// schemaVersion returns the latest migration applied to db, or "" if none is.
+func schemaVersion(ctx context.Context, db *sql.DB) (string, error) {
+	var version sql.NullString
+	err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return "", err
+	}
+	return version.String, nil
+}
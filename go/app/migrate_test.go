@@ -0,0 +1,197 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "mercari.sqlite3"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	applied, err := migrate(t.Context(), db)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if len(applied) == 0 {
+		t.Fatal("expected migrations to be applied")
+	}
+	version, err := schemaVersion(t.Context(), db)
+	if err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+	if want := applied[len(applied)-1]; version != want {
+		t.Errorf("expected schema version %q, got %q", want, version)
+	}
+
+	// applying again is a no-op
+	applied, err = migrate(t.Context(), db)
+	if err != nil {
+		t.Fatalf("failed to migrate again: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected no migrations to be applied, got %v", applied)
+	}
+}
+
+// describeSchema returns the columns, keys, indexes and triggers of every table in db,
+// which are the same for the same schema however its SQL is written.
+func describeSchema(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	query := func(q string, args ...any) [][]string {
+		t.Helper()
+		rows, err := db.Query(q, args...)
+		if err != nil {
+			t.Fatalf("failed to query %q: %v", q, err)
+		}
+		defer rows.Close()
+		cols, _ := rows.Columns()
+		var result [][]string
+		for rows.Next() {
+			values := make([]sql.NullString, len(cols))
+			ptrs := make([]any, len(cols))
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				t.Fatalf("failed to scan %q: %v", q, err)
+			}
+			row := make([]string, len(cols))
+			for i, v := range values {
+				row[i] = v.String
+			}
+			result = append(result, row)
+		}
+		return result
+	}
+
+	var schema []string
+	objects := query(`SELECT type, name, tbl_name FROM sqlite_master
+        WHERE name NOT LIKE 'sqlite_%' AND tbl_name != 'schema_migrations' ORDER BY type, name`)
+	for _, obj := range objects {
+		typ, name := obj[0], obj[1]
+		schema = append(schema, fmt.Sprint(obj))
+		switch typ {
+		case "table":
+			for _, pragma := range []string{"table_info", "foreign_key_list"} {
+				for _, row := range query("SELECT * FROM pragma_"+pragma+"(?)", name) {
+					schema = append(schema, fmt.Sprint(name, " ", pragma, " ", row))
+				}
+			}
+			// the unique constraints are indexes without a name of their own
+			for _, index := range query("SELECT name, \"unique\", origin FROM pragma_index_list(?) ORDER BY name", name) {
+				cols := query("SELECT name FROM pragma_index_info(?) ORDER BY seqno", index[0])
+				schema = append(schema, fmt.Sprint(name, " index ", index[1:], " ", cols))
+			}
+		case "index", "trigger":
+			sql := query("SELECT sql FROM sqlite_master WHERE name = ?", name)
+			schema = append(schema, fmt.Sprint(sql))
+		}
+	}
+	return schema
+}
+
+// TestReferenceSchema checks that db/items.sql is the schema after applying every migration.
+func TestReferenceSchema(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	migrated, err := sql.Open("sqlite3", filepath.Join(dir, "migrated.sqlite3"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer migrated.Close()
+	if _, err := migrate(t.Context(), migrated); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	script, err := os.ReadFile("../db/items.sql")
+	if err != nil {
+		t.Fatalf("failed to read reference schema: %v", err)
+	}
+	reference, err := sql.Open("sqlite3", filepath.Join(dir, "reference.sqlite3"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer reference.Close()
+	if _, err := reference.Exec(string(script)); err != nil {
+		t.Fatalf("failed to create reference schema: %v", err)
+	}
+
+	if diff := cmp.Diff(describeSchema(t, migrated), describeSchema(t, reference)); diff != "" {
+		t.Errorf("db/items.sql differs from the migrations (-migrations +items.sql):\n%s", diff)
+	}
+}
+
+func TestGCImages(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s := Server{ImageDirPath: dir, DBPath: filepath.Join(dir, "mercari.sqlite3")}
+	if code := s.Migrate(nil); code != 0 {
+		t.Fatalf("failed to migrate: exit code %d", code)
+	}
+	db, err := s.openDB()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	used := strings.Repeat("a", 64) + ".jpg"
+	unused := strings.Repeat("b", 64) + ".jpg"
+	recent := strings.Repeat("c", 64) + ".jpg"
+	if err := newTestItemRepository(t, db).Insert(t.Context(), &Item{Name: "jacket", Category: "fashion", Image: used}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+	imageRepo := NewImageRepository(db)
+	if err := imageRepo.Save(t.Context(), &ImageInfo{Name: unused}); err != nil {
+		t.Fatalf("failed to save image: %v", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{"default.jpg", used, unused, recent, ".gitignore", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatalf("failed to write image: %v", err)
+		}
+		if name != recent {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatalf("failed to change times: %v", err)
+			}
+		}
+	}
+
+	if code := s.GCImages([]string{"-dry-run"}); code != 0 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, unused)); err != nil {
+		t.Errorf("expected dry run to keep the unused image: %v", err)
+	}
+
+	if code := s.GCImages(nil); code != 0 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	for name, wantExists := range map[string]bool{"default.jpg": true, used: true, unused: false, recent: true, ".gitignore": true, "notes.txt": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("%s: expected exists %v, got %v", name, wantExists, exists)
+		}
+	}
+	if _, err := imageRepo.Load(t.Context(), unused); !errors.Is(err, errImageNotFound) {
+		t.Errorf("expected the record of the unused image to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+-- the tables of STEP 5. IF NOT EXISTS keeps the databases created by hand before migrations.
+CREATE TABLE IF NOT EXISTS categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    category_id INTEGER NOT NULL,
+    image TEXT NOT NULL,
+    FOREIGN KEY (category_id) REFERENCES categories(id)
+);
@@ -0,0 +1,13 @@
+-- the responses of POST /items replayed for the retries with the same Idempotency-Key header.
+-- status_code is 0 while the first request is in progress.
+CREATE TABLE idempotency_keys (
+    user_id INTEGER NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    body BLOB,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX idempotency_keys_created_at ON idempotency_keys(created_at);
@@ -0,0 +1,16 @@
+-- the admins review the listings flagged by the moderation rules.
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- the audit trail of the moderation: the flags by the rules and the decisions of the admins.
+-- actor_id is NULL for the flags by the rules.
+CREATE TABLE moderation_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    actor_id INTEGER,
+    reasons TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+
+CREATE INDEX moderation_events_item_id ON moderation_events(item_id);
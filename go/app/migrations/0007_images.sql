@@ -0,0 +1,10 @@
+-- the images stored, with their dimensions after applying the EXIF orientation, and their perceptual hash.
+-- name is the name of the file stored, and hash is its 64-bit difference hash,
+-- to find the listings of the same photo re-encoded.
+-- the images stored before have no row.
+CREATE TABLE images (
+    name TEXT PRIMARY KEY,
+    hash INTEGER NOT NULL,
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0
+);
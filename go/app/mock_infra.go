@@ -7,6 +7,7 @@ package app
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +35,34 @@ func (m *MockItemRepository) EXPECT() *MockItemRepositoryMockRecorder {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *MockItemRepository) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockItemRepositoryMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockItemRepository)(nil).Close))
+}
+
+// ForEachItem mocks base method.
+func (m *MockItemRepository) ForEachItem(ctx context.Context, viewerID int, fn func(*Item) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForEachItem", ctx, viewerID, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForEachItem indicates an expected call of ForEachItem.
+func (mr *MockItemRepositoryMockRecorder) ForEachItem(ctx, viewerID, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForEachItem", reflect.TypeOf((*MockItemRepository)(nil).ForEachItem), ctx, viewerID, fn)
+}
+
 // Insert mocks base method.
 func (m *MockItemRepository) Insert(ctx context.Context, item *Item) error {
 	m.ctrl.T.Helper()
@@ -48,32 +77,755 @@ func (mr *MockItemRepositoryMockRecorder) Insert(ctx, item interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockItemRepository)(nil).Insert), ctx, item)
 }
 
+// InsertItems mocks base method.
+func (m *MockItemRepository) InsertItems(ctx context.Context, items []*Item) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertItems", ctx, items)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertItems indicates an expected call of InsertItems.
+func (mr *MockItemRepositoryMockRecorder) InsertItems(ctx, items interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertItems", reflect.TypeOf((*MockItemRepository)(nil).InsertItems), ctx, items)
+}
+
+// LoadCategories mocks base method.
+func (m *MockItemRepository) LoadCategories(ctx context.Context) ([]*Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadCategories", ctx)
+	ret0, _ := ret[0].([]*Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadCategories indicates an expected call of LoadCategories.
+func (mr *MockItemRepositoryMockRecorder) LoadCategories(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadCategories", reflect.TypeOf((*MockItemRepository)(nil).LoadCategories), ctx)
+}
+
+// LoadImageNames mocks base method.
+func (m *MockItemRepository) LoadImageNames(ctx context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadImageNames", ctx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadImageNames indicates an expected call of LoadImageNames.
+func (mr *MockItemRepositoryMockRecorder) LoadImageNames(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadImageNames", reflect.TypeOf((*MockItemRepository)(nil).LoadImageNames), ctx)
+}
+
+// LoadItem mocks base method.
+func (m *MockItemRepository) LoadItem(ctx context.Context, itemID, viewerID int) (*Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadItem", ctx, itemID, viewerID)
+	ret0, _ := ret[0].(*Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadItem indicates an expected call of LoadItem.
+func (mr *MockItemRepositoryMockRecorder) LoadItem(ctx, itemID, viewerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadItem", reflect.TypeOf((*MockItemRepository)(nil).LoadItem), ctx, itemID, viewerID)
+}
+
 // LoadItems mocks base method.
-func (m *MockItemRepository) LoadItems(ctx context.Context) ([]*Item, error) {
+func (m *MockItemRepository) LoadItems(ctx context.Context, viewerID int) ([]*Item, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LoadItems", ctx)
+	ret := m.ctrl.Call(m, "LoadItems", ctx, viewerID)
 	ret0, _ := ret[0].([]*Item)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // LoadItems indicates an expected call of LoadItems.
-func (mr *MockItemRepositoryMockRecorder) LoadItems(ctx interface{}) *gomock.Call {
+func (mr *MockItemRepositoryMockRecorder) LoadItems(ctx, viewerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadItems", reflect.TypeOf((*MockItemRepository)(nil).LoadItems), ctx, viewerID)
+}
+
+// LoadItemsInCategories mocks base method.
+func (m *MockItemRepository) LoadItemsInCategories(ctx context.Context, categories []string, viewerID int) ([]*Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadItemsInCategories", ctx, categories, viewerID)
+	ret0, _ := ret[0].([]*Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadItemsInCategories indicates an expected call of LoadItemsInCategories.
+func (mr *MockItemRepositoryMockRecorder) LoadItemsInCategories(ctx, categories, viewerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadItemsInCategories", reflect.TypeOf((*MockItemRepository)(nil).LoadItemsInCategories), ctx, categories, viewerID)
+}
+
+// Publish mocks base method.
+func (m *MockItemRepository) Publish(ctx context.Context, itemID, sellerID int, publishAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx, itemID, sellerID, publishAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockItemRepositoryMockRecorder) Publish(ctx, itemID, sellerID, publishAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadItems", reflect.TypeOf((*MockItemRepository)(nil).LoadItems), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockItemRepository)(nil).Publish), ctx, itemID, sellerID, publishAt)
+}
+
+// PublishDueItems mocks base method.
+func (m *MockItemRepository) PublishDueItems(ctx context.Context, now time.Time) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishDueItems", ctx, now)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PublishDueItems indicates an expected call of PublishDueItems.
+func (mr *MockItemRepositoryMockRecorder) PublishDueItems(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishDueItems", reflect.TypeOf((*MockItemRepository)(nil).PublishDueItems), ctx, now)
 }
 
 // SearchItems mocks base method.
-func (m *MockItemRepository) SearchItems(ctx context.Context, keyword string) ([]*Item, error) {
+func (m *MockItemRepository) SearchItems(ctx context.Context, keyword string, viewerID int) ([]*Item, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SearchItems", ctx, keyword)
+	ret := m.ctrl.Call(m, "SearchItems", ctx, keyword, viewerID)
 	ret0, _ := ret[0].([]*Item)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SearchItems indicates an expected call of SearchItems.
-func (mr *MockItemRepositoryMockRecorder) SearchItems(ctx, keyword interface{}) *gomock.Call {
+func (mr *MockItemRepositoryMockRecorder) SearchItems(ctx, keyword, viewerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchItems", reflect.TypeOf((*MockItemRepository)(nil).SearchItems), ctx, keyword, viewerID)
+}
+
+// Update mocks base method.
+func (m *MockItemRepository) Update(ctx context.Context, item *Item) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, item)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockItemRepositoryMockRecorder) Update(ctx, item interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockItemRepository)(nil).Update), ctx, item)
+}
+
+// MockUserRepository is a mock of UserRepository interface.
+type MockUserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserRepositoryMockRecorder
+}
+
+// MockUserRepositoryMockRecorder is the mock recorder for MockUserRepository.
+type MockUserRepositoryMockRecorder struct {
+	mock *MockUserRepository
+}
+
+// NewMockUserRepository creates a new mock instance.
+func NewMockUserRepository(ctrl *gomock.Controller) *MockUserRepository {
+	mock := &MockUserRepository{ctrl: ctrl}
+	mock.recorder = &MockUserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Insert mocks base method.
+func (m *MockUserRepository) Insert(ctx context.Context, user *User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockUserRepositoryMockRecorder) Insert(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockUserRepository)(nil).Insert), ctx, user)
+}
+
+// LoadUsers mocks base method.
+func (m *MockUserRepository) LoadUsers(ctx context.Context, userIDs []int) ([]*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadUsers", ctx, userIDs)
+	ret0, _ := ret[0].([]*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadUsers indicates an expected call of LoadUsers.
+func (mr *MockUserRepositoryMockRecorder) LoadUsers(ctx, userIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadUsers", reflect.TypeOf((*MockUserRepository)(nil).LoadUsers), ctx, userIDs)
+}
+
+// MockLikeRepository is a mock of LikeRepository interface.
+type MockLikeRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLikeRepositoryMockRecorder
+}
+
+// MockLikeRepositoryMockRecorder is the mock recorder for MockLikeRepository.
+type MockLikeRepositoryMockRecorder struct {
+	mock *MockLikeRepository
+}
+
+// NewMockLikeRepository creates a new mock instance.
+func NewMockLikeRepository(ctrl *gomock.Controller) *MockLikeRepository {
+	mock := &MockLikeRepository{ctrl: ctrl}
+	mock.recorder = &MockLikeRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLikeRepository) EXPECT() *MockLikeRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Like mocks base method.
+func (m *MockLikeRepository) Like(ctx context.Context, userID, itemID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Like", ctx, userID, itemID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Like indicates an expected call of Like.
+func (mr *MockLikeRepositoryMockRecorder) Like(ctx, userID, itemID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Like", reflect.TypeOf((*MockLikeRepository)(nil).Like), ctx, userID, itemID)
+}
+
+// LoadLikedItemIDs mocks base method.
+func (m *MockLikeRepository) LoadLikedItemIDs(ctx context.Context, userID int, itemIDs []int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadLikedItemIDs", ctx, userID, itemIDs)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadLikedItemIDs indicates an expected call of LoadLikedItemIDs.
+func (mr *MockLikeRepositoryMockRecorder) LoadLikedItemIDs(ctx, userID, itemIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLikedItemIDs", reflect.TypeOf((*MockLikeRepository)(nil).LoadLikedItemIDs), ctx, userID, itemIDs)
+}
+
+// LoadLikedItems mocks base method.
+func (m *MockLikeRepository) LoadLikedItems(ctx context.Context, userID int) ([]*Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadLikedItems", ctx, userID)
+	ret0, _ := ret[0].([]*Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadLikedItems indicates an expected call of LoadLikedItems.
+func (mr *MockLikeRepositoryMockRecorder) LoadLikedItems(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLikedItems", reflect.TypeOf((*MockLikeRepository)(nil).LoadLikedItems), ctx, userID)
+}
+
+// Unlike mocks base method.
+func (m *MockLikeRepository) Unlike(ctx context.Context, userID, itemID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlike", ctx, userID, itemID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlike indicates an expected call of Unlike.
+func (mr *MockLikeRepositoryMockRecorder) Unlike(ctx, userID, itemID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlike", reflect.TypeOf((*MockLikeRepository)(nil).Unlike), ctx, userID, itemID)
+}
+
+// MockCommentRepository is a mock of CommentRepository interface.
+type MockCommentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCommentRepositoryMockRecorder
+}
+
+// MockCommentRepositoryMockRecorder is the mock recorder for MockCommentRepository.
+type MockCommentRepositoryMockRecorder struct {
+	mock *MockCommentRepository
+}
+
+// NewMockCommentRepository creates a new mock instance.
+func NewMockCommentRepository(ctrl *gomock.Controller) *MockCommentRepository {
+	mock := &MockCommentRepository{ctrl: ctrl}
+	mock.recorder = &MockCommentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCommentRepository) EXPECT() *MockCommentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockCommentRepository) Delete(ctx context.Context, commentID, userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, commentID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCommentRepositoryMockRecorder) Delete(ctx, commentID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCommentRepository)(nil).Delete), ctx, commentID, userID)
+}
+
+// Insert mocks base method.
+func (m *MockCommentRepository) Insert(ctx context.Context, comment *Comment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, comment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockCommentRepositoryMockRecorder) Insert(ctx, comment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockCommentRepository)(nil).Insert), ctx, comment)
+}
+
+// LoadComments mocks base method.
+func (m *MockCommentRepository) LoadComments(ctx context.Context, itemID, limit, offset int) ([]*Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadComments", ctx, itemID, limit, offset)
+	ret0, _ := ret[0].([]*Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadComments indicates an expected call of LoadComments.
+func (mr *MockCommentRepositoryMockRecorder) LoadComments(ctx, itemID, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadComments", reflect.TypeOf((*MockCommentRepository)(nil).LoadComments), ctx, itemID, limit, offset)
+}
+
+// MockWebhookRepository is a mock of WebhookRepository interface.
+type MockWebhookRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookRepositoryMockRecorder
+}
+
+// MockWebhookRepositoryMockRecorder is the mock recorder for MockWebhookRepository.
+type MockWebhookRepositoryMockRecorder struct {
+	mock *MockWebhookRepository
+}
+
+// NewMockWebhookRepository creates a new mock instance.
+func NewMockWebhookRepository(ctrl *gomock.Controller) *MockWebhookRepository {
+	mock := &MockWebhookRepository{ctrl: ctrl}
+	mock.recorder = &MockWebhookRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookRepository) EXPECT() *MockWebhookRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockWebhookRepository) DeleteWebhook(ctx context.Context, webhookID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, webhookID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockWebhookRepositoryMockRecorder) DeleteWebhook(ctx, webhookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockWebhookRepository)(nil).DeleteWebhook), ctx, webhookID)
+}
+
+// InsertDelivery mocks base method.
+func (m *MockWebhookRepository) InsertDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertDelivery indicates an expected call of InsertDelivery.
+func (mr *MockWebhookRepositoryMockRecorder) InsertDelivery(ctx, delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDelivery", reflect.TypeOf((*MockWebhookRepository)(nil).InsertDelivery), ctx, delivery)
+}
+
+// InsertWebhook mocks base method.
+func (m *MockWebhookRepository) InsertWebhook(ctx context.Context, hook *Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertWebhook", ctx, hook)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertWebhook indicates an expected call of InsertWebhook.
+func (mr *MockWebhookRepositoryMockRecorder) InsertWebhook(ctx, hook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertWebhook", reflect.TypeOf((*MockWebhookRepository)(nil).InsertWebhook), ctx, hook)
+}
+
+// LoadDeliveries mocks base method.
+func (m *MockWebhookRepository) LoadDeliveries(ctx context.Context, webhookID int) ([]*WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadDeliveries", ctx, webhookID)
+	ret0, _ := ret[0].([]*WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadDeliveries indicates an expected call of LoadDeliveries.
+func (mr *MockWebhookRepositoryMockRecorder) LoadDeliveries(ctx, webhookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadDeliveries", reflect.TypeOf((*MockWebhookRepository)(nil).LoadDeliveries), ctx, webhookID)
+}
+
+// LoadWebhooks mocks base method.
+func (m *MockWebhookRepository) LoadWebhooks(ctx context.Context) ([]*Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadWebhooks", ctx)
+	ret0, _ := ret[0].([]*Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadWebhooks indicates an expected call of LoadWebhooks.
+func (mr *MockWebhookRepositoryMockRecorder) LoadWebhooks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadWebhooks", reflect.TypeOf((*MockWebhookRepository)(nil).LoadWebhooks), ctx)
+}
+
+// LoadWebhooksForEvent mocks base method.
+func (m *MockWebhookRepository) LoadWebhooksForEvent(ctx context.Context, eventType string) ([]*Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadWebhooksForEvent", ctx, eventType)
+	ret0, _ := ret[0].([]*Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadWebhooksForEvent indicates an expected call of LoadWebhooksForEvent.
+func (mr *MockWebhookRepositoryMockRecorder) LoadWebhooksForEvent(ctx, eventType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadWebhooksForEvent", reflect.TypeOf((*MockWebhookRepository)(nil).LoadWebhooksForEvent), ctx, eventType)
+}
+
+// MockIdempotencyKeyRepository is a mock of IdempotencyKeyRepository interface.
+type MockIdempotencyKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyKeyRepositoryMockRecorder
+}
+
+// MockIdempotencyKeyRepositoryMockRecorder is the mock recorder for MockIdempotencyKeyRepository.
+type MockIdempotencyKeyRepositoryMockRecorder struct {
+	mock *MockIdempotencyKeyRepository
+}
+
+// NewMockIdempotencyKeyRepository creates a new mock instance.
+func NewMockIdempotencyKeyRepository(ctrl *gomock.Controller) *MockIdempotencyKeyRepository {
+	mock := &MockIdempotencyKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyKeyRepository) EXPECT() *MockIdempotencyKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Complete mocks base method.
+func (m *MockIdempotencyKeyRepository) Complete(ctx context.Context, key *IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockIdempotencyKeyRepositoryMockRecorder) Complete(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIdempotencyKeyRepository)(nil).Complete), ctx, key)
+}
+
+// Purge mocks base method.
+func (m *MockIdempotencyKeyRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockIdempotencyKeyRepositoryMockRecorder) Purge(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockIdempotencyKeyRepository)(nil).Purge), ctx, before)
+}
+
+// Release mocks base method.
+func (m *MockIdempotencyKeyRepository) Release(ctx context.Context, userID int, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, userID, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockIdempotencyKeyRepositoryMockRecorder) Release(ctx, userID, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockIdempotencyKeyRepository)(nil).Release), ctx, userID, key)
+}
+
+// Reserve mocks base method.
+func (m *MockIdempotencyKeyRepository) Reserve(ctx context.Context, key *IdempotencyKey, since time.Time) (*IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, key, since)
+	ret0, _ := ret[0].(*IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockIdempotencyKeyRepositoryMockRecorder) Reserve(ctx, key, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockIdempotencyKeyRepository)(nil).Reserve), ctx, key, since)
+}
+
+// MockModerationRepository is a mock of ModerationRepository interface.
+type MockModerationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockModerationRepositoryMockRecorder
+}
+
+// MockModerationRepositoryMockRecorder is the mock recorder for MockModerationRepository.
+type MockModerationRepositoryMockRecorder struct {
+	mock *MockModerationRepository
+}
+
+// NewMockModerationRepository creates a new mock instance.
+func NewMockModerationRepository(ctrl *gomock.Controller) *MockModerationRepository {
+	mock := &MockModerationRepository{ctrl: ctrl}
+	mock.recorder = &MockModerationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModerationRepository) EXPECT() *MockModerationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Approve mocks base method.
+func (m *MockModerationRepository) Approve(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Approve", ctx, itemID, adminID, note)
+	ret0, _ := ret[0].(*ModerationEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Approve indicates an expected call of Approve.
+func (mr *MockModerationRepositoryMockRecorder) Approve(ctx, itemID, adminID, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Approve", reflect.TypeOf((*MockModerationRepository)(nil).Approve), ctx, itemID, adminID, note)
+}
+
+// Flag mocks base method.
+func (m *MockModerationRepository) Flag(ctx context.Context, itemID int, reasons []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flag", ctx, itemID, reasons)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flag indicates an expected call of Flag.
+func (mr *MockModerationRepositoryMockRecorder) Flag(ctx, itemID, reasons interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flag", reflect.TypeOf((*MockModerationRepository)(nil).Flag), ctx, itemID, reasons)
+}
+
+// LoadEvents mocks base method.
+func (m *MockModerationRepository) LoadEvents(ctx context.Context, itemID int) ([]*ModerationEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadEvents", ctx, itemID)
+	ret0, _ := ret[0].([]*ModerationEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadEvents indicates an expected call of LoadEvents.
+func (mr *MockModerationRepositoryMockRecorder) LoadEvents(ctx, itemID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadEvents", reflect.TypeOf((*MockModerationRepository)(nil).LoadEvents), ctx, itemID)
+}
+
+// LoadItemsInReview mocks base method.
+func (m *MockModerationRepository) LoadItemsInReview(ctx context.Context) ([]*ItemInReview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadItemsInReview", ctx)
+	ret0, _ := ret[0].([]*ItemInReview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadItemsInReview indicates an expected call of LoadItemsInReview.
+func (mr *MockModerationRepositoryMockRecorder) LoadItemsInReview(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadItemsInReview", reflect.TypeOf((*MockModerationRepository)(nil).LoadItemsInReview), ctx)
+}
+
+// Reject mocks base method.
+func (m *MockModerationRepository) Reject(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reject", ctx, itemID, adminID, note)
+	ret0, _ := ret[0].(*ModerationEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reject indicates an expected call of Reject.
+func (mr *MockModerationRepositoryMockRecorder) Reject(ctx, itemID, adminID, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reject", reflect.TypeOf((*MockModerationRepository)(nil).Reject), ctx, itemID, adminID, note)
+}
+
+// MockAuditLogRepository is a mock of AuditLogRepository interface.
+type MockAuditLogRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditLogRepositoryMockRecorder
+}
+
+// MockAuditLogRepositoryMockRecorder is the mock recorder for MockAuditLogRepository.
+type MockAuditLogRepositoryMockRecorder struct {
+	mock *MockAuditLogRepository
+}
+
+// NewMockAuditLogRepository creates a new mock instance.
+func NewMockAuditLogRepository(ctrl *gomock.Controller) *MockAuditLogRepository {
+	mock := &MockAuditLogRepository{ctrl: ctrl}
+	mock.recorder = &MockAuditLogRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditLogRepository) EXPECT() *MockAuditLogRepositoryMockRecorder {
+	return m.recorder
+}
+
+// LoadAuditLog mocks base method.
+func (m *MockAuditLogRepository) LoadAuditLog(ctx context.Context, filter AuditLogFilter) ([]*AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadAuditLog", ctx, filter)
+	ret0, _ := ret[0].([]*AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadAuditLog indicates an expected call of LoadAuditLog.
+func (mr *MockAuditLogRepositoryMockRecorder) LoadAuditLog(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadAuditLog", reflect.TypeOf((*MockAuditLogRepository)(nil).LoadAuditLog), ctx, filter)
+}
+
+// MockImageRepository is a mock of ImageRepository interface.
+type MockImageRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockImageRepositoryMockRecorder
+}
+
+// MockImageRepositoryMockRecorder is the mock recorder for MockImageRepository.
+type MockImageRepositoryMockRecorder struct {
+	mock *MockImageRepository
+}
+
+// NewMockImageRepository creates a new mock instance.
+func NewMockImageRepository(ctrl *gomock.Controller) *MockImageRepository {
+	mock := &MockImageRepository{ctrl: ctrl}
+	mock.recorder = &MockImageRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImageRepository) EXPECT() *MockImageRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockImageRepository) Delete(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockImageRepositoryMockRecorder) Delete(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockImageRepository)(nil).Delete), ctx, name)
+}
+
+// Load mocks base method.
+func (m *MockImageRepository) Load(ctx context.Context, name string) (*ImageInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load", ctx, name)
+	ret0, _ := ret[0].(*ImageInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Load indicates an expected call of Load.
+func (mr *MockImageRepositoryMockRecorder) Load(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockImageRepository)(nil).Load), ctx, name)
+}
+
+// LoadSimilarItems mocks base method.
+func (m *MockImageRepository) LoadSimilarItems(ctx context.Context, hash uint64, maxDistance, viewerID int) ([]*Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadSimilarItems", ctx, hash, maxDistance, viewerID)
+	ret0, _ := ret[0].([]*Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadSimilarItems indicates an expected call of LoadSimilarItems.
+func (mr *MockImageRepositoryMockRecorder) LoadSimilarItems(ctx, hash, maxDistance, viewerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadSimilarItems", reflect.TypeOf((*MockImageRepository)(nil).LoadSimilarItems), ctx, hash, maxDistance, viewerID)
+}
+
+// Save mocks base method.
+func (m *MockImageRepository) Save(ctx context.Context, image *ImageInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, image)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockImageRepositoryMockRecorder) Save(ctx, image interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchItems", reflect.TypeOf((*MockItemRepository)(nil).SearchItems), ctx, keyword)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockImageRepository)(nil).Save), ctx, image)
 }
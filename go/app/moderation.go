@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ModerationInput is the listing checked by the moderation rules.
+type ModerationInput struct {
+	Name     string
+	Category string
+	// ImageHash is the SHA-256 of the image stored in hex, the same as the name of its file without the extension.
+	// The image stored is the upload re-encoded by sanitizeImage, so the hash is not the one of the upload.
+	ImageHash string
+}
+
+// ModerationRule is a check of the listings. Implement it to add a rule to the moderation.
+type ModerationRule interface {
+	// Check returns the reasons to review the listing, or none if the listing passes the rule.
+	Check(input ModerationInput) []string
+}
+
+// ModerationRuleFunc is a function used as a ModerationRule.
+type ModerationRuleFunc func(input ModerationInput) []string
+
+func (f ModerationRuleFunc) Check(input ModerationInput) []string {
+	return f(input)
+}
+
+// BannedWordsRule flags the listings whose name contains any of the words, ignoring the case.
+func BannedWordsRule(words []string) ModerationRule {
+	lowered := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			lowered = append(lowered, word)
+		}
+	}
+	return ModerationRuleFunc(func(input ModerationInput) []string {
+		name := strings.ToLower(input.Name)
+		var reasons []string
+		for _, word := range lowered {
+			if strings.Contains(name, word) {
+				reasons = append(reasons, fmt.Sprintf("the name contains the banned word %q", word))
+			}
+		}
+		return reasons
+	})
+}
+
+// CategoryRule flags the listings in any of the categories, which always need a review, such as tickets.
+func CategoryRule(categories []string) ModerationRule {
+	return ModerationRuleFunc(func(input ModerationInput) []string {
+		if slices.ContainsFunc(categories, func(c string) bool { return strings.EqualFold(c, input.Category) }) {
+			return []string{fmt.Sprintf("the category %q needs a review", input.Category)}
+		}
+		return nil
+	})
+}
+
+// ImageHashBlocklistRule flags the listings whose image is any of the blocked ones, given as SHA-256 in hex.
+func ImageHashBlocklistRule(hashes []string) ModerationRule {
+	blocked := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		blocked[strings.ToLower(strings.TrimSpace(h))] = true
+	}
+	return ModerationRuleFunc(func(input ModerationInput) []string {
+		if input.ImageHash != "" && blocked[input.ImageHash] {
+			return []string{"the image is blocked"}
+		}
+		return nil
+	})
+}
+
+// ModerationConfig is the configuration of the built-in moderation rules, read from a JSON file.
+type ModerationConfig struct {
+	BannedWords        []string `json:"banned_words"`
+	ReviewCategories   []string `json:"review_categories"`
+	BlockedImageHashes []string `json:"blocked_image_hashes"`
+}
+
+// loadModerationConfig reads the configuration of the moderation from the JSON file.
+func loadModerationConfig(name string) (ModerationConfig, error) {
+	var cfg ModerationConfig
+	f, err := os.Open(name)
+	if err != nil {
+		return cfg, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("invalid moderation config %s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// Rules returns the rules configured, which are none for the zero value.
+func (c ModerationConfig) Rules() []ModerationRule {
+	var rules []ModerationRule
+	if len(c.BannedWords) > 0 {
+		rules = append(rules, BannedWordsRule(c.BannedWords))
+	}
+	if len(c.ReviewCategories) > 0 {
+		rules = append(rules, CategoryRule(c.ReviewCategories))
+	}
+	if len(c.BlockedImageHashes) > 0 {
+		rules = append(rules, ImageHashBlocklistRule(c.BlockedImageHashes))
+	}
+	return rules
+}
+
+// itemModeration checks the new and updated items against the rules, and records the flags with repo.
+// A nil itemModeration passes every item.
+type itemModeration struct {
+	rules []ModerationRule
+	repo  ModerationRepository
+}
+
+func newItemModeration(rules []ModerationRule, repo ModerationRepository) *itemModeration {
+	return &itemModeration{rules: rules, repo: repo}
+}
+
+// check checks the item against the rules and returns the reasons to review it.
+// The status of a flagged item becomes itemStatusPendingReview, unless it is rejected already.
+// The reasons are set to the item too, for ItemRepository to flag a new item as it inserts it;
+// call flag with them before updating an existing item.
+func (m *itemModeration) check(item *Item) []string {
+	if m == nil {
+		return nil
+	}
+	input := ModerationInput{
+		Name:      item.Name,
+		Category:  item.Category,
+		ImageHash: strings.TrimSuffix(item.Image, path.Ext(item.Image)),
+	}
+	var reasons []string
+	for _, rule := range m.rules {
+		reasons = append(reasons, rule.Check(input)...)
+	}
+	if len(reasons) > 0 && item.Status != itemStatusRejected {
+		item.Status = itemStatusPendingReview
+	}
+	item.ModerationReasons = reasons
+	return reasons
+}
+
+// flag puts the item in review for the reasons returned by check, if any.
+func (m *itemModeration) flag(ctx context.Context, itemID int, reasons []string) error {
+	if m == nil || len(reasons) == 0 {
+		return nil
+	}
+	return m.repo.Flag(ctx, itemID, reasons)
+}
+
+// adminTokenHeader is the header carrying the admin token, which X-User-ID alone cannot prove since anyone can set it.
+const adminTokenHeader = "X-Admin-Token"
+
+// requireAdmin returns the ID of the admin making the request, or writes the error response
+// if the admin token is wrong, or the user is missing or not an admin.
+func (s *Handlers) requireAdmin(w http.ResponseWriter, r *http.Request) (int, bool) {
+	token := r.Header.Get(adminTokenHeader)
+	if s.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+		http.Error(w, "admin only", http.StatusForbidden)
+		return 0, false
+	}
+	userID, err := parseUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, false
+	}
+	users, err := s.userRepo.LoadUsers(r.Context(), []int{userID})
+	if err != nil {
+		slog.Error("failed to load user", "error", err)
+		http.Error(w, "failed to load user", http.StatusInternalServerError)
+		return 0, false
+	}
+	if len(users) == 0 || !users[0].IsAdmin {
+		http.Error(w, "admin only", http.StatusForbidden)
+		return 0, false
+	}
+	return userID, true
+}
+
+// GetItemsInReview is a handler to return the items flagged by the moderation for GET /admin/moderation/items .
+func (s *Handlers) GetItemsInReview(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	items, err := s.moderationRepo.LoadItemsInReview(r.Context())
+	if err != nil {
+		slog.Error("failed to load items in review", "error", err)
+		http.Error(w, "failed to load items in review", http.StatusInternalServerError)
+		return
+	}
+	for _, item := range items {
+		item.Item = s.imageSigner.withImageURL(item.Item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"items": items}); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// GetModerationEvents is a handler to return the audit trail of the moderation of an item
+// for GET /admin/moderation/items/{item_id}/events .
+func (s *Handlers) GetModerationEvents(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	itemID, err := strconv.Atoi(r.PathValue("item_id"))
+	if err != nil || itemID <= 0 {
+		http.Error(w, "invalid item ID", http.StatusBadRequest)
+		return
+	}
+	events, err := s.moderationRepo.LoadEvents(r.Context(), itemID)
+	if err != nil {
+		slog.Error("failed to load moderation events", "error", err)
+		http.Error(w, "failed to load moderation events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"events": events}); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// ApproveItem is a handler to return an item in review to its seller as a draft
+// for POST /admin/moderation/items/{item_id}/approve . The optional "note" form value is recorded with the decision.
+func (s *Handlers) ApproveItem(w http.ResponseWriter, r *http.Request) {
+	s.reviewItem(w, r, s.moderationRepo.Approve, itemStatusDraft)
+}
+
+// RejectItem is a handler to reject an item in review for POST /admin/moderation/items/{item_id}/reject .
+// The optional "note" form value is recorded with the decision.
+func (s *Handlers) RejectItem(w http.ResponseWriter, r *http.Request) {
+	s.reviewItem(w, r, s.moderationRepo.Reject, itemStatusRejected)
+}
+
+// reviewItem records the decision of the admin with review, which changes the status of the item to status.
+func (s *Handlers) reviewItem(w http.ResponseWriter, r *http.Request, review func(ctx context.Context, itemID, adminID int, note string) (*ModerationEvent, error), status string) {
+	adminID, ok := s.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.Atoi(r.PathValue("item_id"))
+	if err != nil || itemID <= 0 {
+		http.Error(w, "invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	event, err := review(r.Context(), itemID, adminID, strings.TrimSpace(r.FormValue("note")))
+	if errors.Is(err, errItemNotFound) {
+		http.Error(w, "item not found in review", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to review item", "error", err)
+		http.Error(w, "failed to review item", http.StatusInternalServerError)
+		return
+	}
+	s.events.PublishStatusChange(&Item{ID: itemID, Status: status})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(event); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
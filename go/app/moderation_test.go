@@ -0,0 +1,257 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestModerationRules(t *testing.T) {
+	t.Parallel()
+
+	blockedHash := strings.Repeat("ab", 32)
+	moderation := newItemModeration(ModerationConfig{
+		BannedWords:        []string{"Replica", " "},
+		ReviewCategories:   []string{"tickets"},
+		BlockedImageHashes: []string{strings.ToUpper(blockedHash)},
+	}.Rules(), nil)
+
+	cases := map[string]struct {
+		item *Item
+
+		wantReasons []string
+		wantStatus  string
+	}{
+		"ok": {
+			item:       &Item{Name: "jacket", Category: "fashion", Image: "default.jpg", Status: itemStatusDraft},
+			wantStatus: itemStatusDraft,
+		},
+		"ng: banned word in any case": {
+			item:        &Item{Name: "REPLICA watch", Category: "fashion", Status: itemStatusDraft},
+			wantReasons: []string{`the name contains the banned word "replica"`},
+			wantStatus:  itemStatusPendingReview,
+		},
+		"ng: category and image": {
+			item:        &Item{Name: "concert", Category: "Tickets", Image: blockedHash + ".jpg", Status: itemStatusPublished},
+			wantReasons: []string{`the category "Tickets" needs a review`, "the image is blocked"},
+			wantStatus:  itemStatusPendingReview,
+		},
+		"ng: rejected stays rejected": {
+			item:        &Item{Name: "replica", Category: "fashion", Status: itemStatusRejected},
+			wantReasons: []string{`the name contains the banned word "replica"`},
+			wantStatus:  itemStatusRejected,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			reasons := moderation.check(tt.item)
+			if diff := cmp.Diff(tt.wantReasons, reasons); diff != "" {
+				t.Errorf("unexpected reasons (-want +got):\n%s", diff)
+			}
+			if tt.item.Status != tt.wantStatus {
+				t.Errorf("expected status %q, got %q", tt.wantStatus, tt.item.Status)
+			}
+		})
+	}
+
+	var disabled *itemModeration
+	if reasons := disabled.check(&Item{Name: "replica"}); reasons != nil {
+		t.Errorf("expected no reasons without moderation, got %v", reasons)
+	}
+}
+
+func TestModerationE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := t.Context()
+	itemRepo := newTestItemRepository(t, db)
+	userRepo := NewUserRepository(db)
+	moderationRepo := NewModerationRepository(db)
+	admin := &User{Name: "admin", Email: "admin@example.com", IsAdmin: true}
+	if err := userRepo.Insert(ctx, admin); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+	h := &Handlers{
+		imgDirPath:     t.TempDir(),
+		itemRepo:       itemRepo,
+		userRepo:       userRepo,
+		moderationRepo: moderationRepo,
+		moderation:     newItemModeration([]ModerationRule{BannedWordsRule([]string{"replica"})}, moderationRepo),
+		events:         NewEventBus(),
+		adminToken:     "secret",
+	}
+
+	// the flagged item is created in review, and cannot be published by the seller
+	rr := httptest.NewRecorder()
+	h.AddItem(rr, newAddItemRequest(t, "", map[string]string{"name": "replica watch", "category": "fashion"}))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	var item Item
+	if err := json.Unmarshal(rr.Body.Bytes(), &item); err != nil {
+		t.Fatalf("failed to decode item: %v", err)
+	}
+	if item.Status != itemStatusPendingReview {
+		t.Errorf("expected status %q, got %q", itemStatusPendingReview, item.Status)
+	}
+	if err := itemRepo.Publish(ctx, item.ID, 1, time.Now()); err == nil {
+		t.Error("expected the item in review not to be published")
+	}
+
+	review := func(method, target, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set(userIDHeader, userID)
+		req.Header.Set(adminTokenHeader, "secret")
+		rr := httptest.NewRecorder()
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /admin/moderation/items", h.GetItemsInReview)
+		mux.HandleFunc("GET /admin/moderation/items/{item_id}/events", h.GetModerationEvents)
+		mux.HandleFunc("POST /admin/moderation/items/{item_id}/approve", h.ApproveItem)
+		mux.HandleFunc("POST /admin/moderation/items/{item_id}/reject", h.RejectItem)
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	adminID := strconv.Itoa(admin.ID)
+
+	if rr := review(http.MethodGet, "/admin/moderation/items", "99"); rr.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for a non-admin, got %d", http.StatusForbidden, rr.Code)
+	}
+	// X-User-ID of an admin is not enough without the admin token
+	for _, token := range []string{"", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/moderation/items", nil)
+		req.Header.Set(userIDHeader, adminID)
+		req.Header.Set(adminTokenHeader, token)
+		rr := httptest.NewRecorder()
+		h.GetItemsInReview(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status %d for the admin token %q, got %d", http.StatusForbidden, token, rr.Code)
+		}
+	}
+	rr = review(http.MethodGet, "/admin/moderation/items", adminID)
+	var inReview struct{ Items []ItemInReview }
+	if err := json.Unmarshal(rr.Body.Bytes(), &inReview); err != nil {
+		t.Fatalf("failed to decode items: %v", err)
+	}
+	if len(inReview.Items) != 1 || inReview.Items[0].ID != item.ID || len(inReview.Items[0].Reasons) != 1 {
+		t.Errorf("unexpected items in review: %s", rr.Body)
+	}
+
+	_, events, unsubscribe := h.events.Subscribe(0)
+	defer unsubscribe()
+	target := "/admin/moderation/items/" + strconv.Itoa(item.ID)
+	if rr := review(http.MethodPost, target+"/approve?note=authentic", adminID); rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	// the stream carries the change of the status
+	if ev := <-events; ev.Type != eventItemStatusChanged || ev.ItemID != item.ID || ev.Item.Status != itemStatusDraft {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+	if rr := review(http.MethodPost, target+"/reject", adminID); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an item not in review, got %d", http.StatusNotFound, rr.Code)
+	}
+	loaded, err := itemRepo.LoadItem(ctx, item.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to load item: %v", err)
+	}
+	if loaded.Status != itemStatusDraft {
+		t.Errorf("expected the approved item to be a draft, got %q", loaded.Status)
+	}
+
+	rr = review(http.MethodGet, target+"/events", adminID)
+	var trail struct{ Events []ModerationEvent }
+	if err := json.Unmarshal(rr.Body.Bytes(), &trail); err != nil {
+		t.Fatalf("failed to decode events: %v", err)
+	}
+	var actions []string
+	for _, e := range trail.Events {
+		actions = append(actions, e.Action)
+	}
+	if diff := cmp.Diff([]string{moderationActionFlagged, moderationActionApproved}, actions); diff != "" {
+		t.Errorf("unexpected audit trail (-want +got):\n%s", diff)
+	}
+	if last := trail.Events[len(trail.Events)-1]; last.ActorID != admin.ID || !cmp.Equal(last.Reasons, []string{"authentic"}) {
+		t.Errorf("unexpected decision: %+v", last)
+	}
+
+	// the changes of the status are in the audit log, the newest first
+	entries, err := NewAuditLogRepository(db).LoadAuditLog(ctx, AuditLogFilter{ItemID: item.ID})
+	if err != nil {
+		t.Fatalf("failed to load audit log: %v", err)
+	}
+	actions = nil
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	if diff := cmp.Diff([]string{auditActionApprove, auditActionCreate}, actions); diff != "" {
+		t.Errorf("unexpected audit log (-want +got):\n%s", diff)
+	}
+	if entries[0].ActorID != admin.ID {
+		t.Errorf("expected the admin to be the actor of the approval, got %d", entries[0].ActorID)
+	}
+}
+
+func TestImportModerationE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	dir := t.TempDir()
+	s := Server{
+		ImageDirPath: dir,
+		DBPath:       filepath.Join(dir, "mercari.sqlite3"),
+		Moderation:   ModerationConfig{BannedWords: []string{"replica"}},
+	}
+	if code := s.Migrate(nil); code != 0 {
+		t.Fatalf("failed to migrate: exit code %d", code)
+	}
+	path := filepath.Join(dir, "items.ndjson")
+	rows := `{"name":"replica watch","category":"fashion"}` + "\n" + `{"name":"jacket","category":"fashion"}` + "\n"
+	if err := os.WriteFile(path, []byte(rows), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// the flagged rows are imported in review even with -publish, like the ones of POST /items:bulk
+	if code := s.Import([]string{"-seller", "1", "-publish", path}); code != 0 {
+		t.Fatalf("failed to import: exit code %d", code)
+	}
+	db, err := s.openDB()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	statuses := map[string]string{}
+	items, err := newTestItemRepository(t, db).LoadItems(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("failed to load items: %v", err)
+	}
+	for _, item := range items {
+		statuses[item.Name] = item.Status
+	}
+	want := map[string]string{"replica watch": itemStatusPendingReview, "jacket": itemStatusPublished}
+	if diff := cmp.Diff(want, statuses); diff != "" {
+		t.Errorf("unexpected statuses (-want +got):\n%s", diff)
+	}
+}
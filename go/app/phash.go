@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"log/slog"
+	"math/bits"
+	"net/http"
+	"strconv"
+)
+
+// maxSimilarImageDistance is the number of bits, out of 64, by which the hashes of similar images can differ.
+// Resizing or re-encoding a photo changes a few bits, while different photos differ by around half of them.
+const maxSimilarImageDistance = 10
+
+// dHashWidth and dHashHeight are the size of the image shrunk to compute a difference hash.
+// Comparing the horizontal neighbours gives 8x8 bits.
+const (
+	dHashWidth  = 9
+	dHashHeight = 8
+)
+
+// dHash shrinks the image to 9x8 gray pixels, and sets a bit for each pixel brighter than its right neighbour.
+// It ignores the size, the compression and small changes of the colours of the image.
+func dHash(img image.Image) uint64 {
+	b := img.Bounds()
+	if b.Empty() {
+		return 0
+	}
+	var gray [dHashHeight][dHashWidth]float64
+	for y := range dHashHeight {
+		y0, y1 := cellRange(b.Min.Y, b.Dy(), y, dHashHeight)
+		for x := range dHashWidth {
+			x0, x1 := cellRange(b.Min.X, b.Dx(), x, dHashWidth)
+			// the average luma of the pixels in the cell
+			var sum float64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, bl, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+				}
+			}
+			gray[y][x] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+
+	var hash uint64
+	for y := range dHashHeight {
+		for x := range dHashWidth - 1 {
+			hash <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// cellRange returns the range of the pixels in the i-th of n cells dividing size pixels from origin.
+// A cell has at least a pixel, even if the image is smaller than the cells.
+func cellRange(origin, size, i, n int) (int, int) {
+	start := origin + i*size/n
+	end := origin + (i+1)*size/n
+	return start, max(end, start+1)
+}
+
+// hashDistance returns the number of the bits differing between the hashes.
+func hashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// saveImage records the dimensions and the perceptual hash of an image stored as fileName.
+// A failure is only logged, since the image is stored already.
+func (s *Handlers) saveImage(ctx context.Context, fileName string, img image.Image) {
+	if s.imageRepo == nil {
+		return
+	}
+	info := &ImageInfo{Name: fileName, Hash: dHash(img), Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if err := s.imageRepo.Save(ctx, info); err != nil {
+		slog.Error("failed to save image", "image", fileName, "error", err)
+	}
+}
+
+// similarItems returns the other items visible to the viewer whose image is similar to the one of the item.
+func (s *Handlers) similarItems(ctx context.Context, item *Item, viewerID int) ([]*Item, error) {
+	items := []*Item{}
+	if s.imageRepo == nil {
+		return items, nil
+	}
+	stored, err := s.imageRepo.Load(ctx, item.Image)
+	if errors.Is(err, errImageNotFound) {
+		return items, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	similar, err := s.imageRepo.LoadSimilarItems(ctx, stored.Hash, maxSimilarImageDistance, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range similar {
+		if other.ID != item.ID {
+			items = append(items, other)
+		}
+	}
+	return items, nil
+}
+
+// GetSimilarItems is a handler to return the listings whose image is similar to the one of an item
+// for GET /items/{item_id}/similar , such as the same photo resized or re-encoded.
+func (s *Handlers) GetSimilarItems(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := parseOptionalUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.Atoi(r.PathValue("item_id"))
+	if err != nil || itemID <= 0 {
+		http.Error(w, "invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.itemRepo.LoadItem(r.Context(), itemID, viewerID)
+	if errors.Is(err, errItemNotFound) {
+		http.Error(w, "item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load item", "error", err)
+		http.Error(w, "failed to load item", http.StatusInternalServerError)
+		return
+	}
+	items, err := s.similarItems(r.Context(), item, viewerID)
+	if err != nil {
+		slog.Error("failed to load similar items", "error", err)
+		http.Error(w, "failed to load similar items", http.StatusInternalServerError)
+		return
+	}
+	items = s.imageSigner.withImageURLs(items)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"items": items}); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testPhoto returns an image with smooth shapes like a photo, which differ by the frequencies fx and fy.
+func testPhoto(width, height int, fx, fy float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			u, v := float64(x)/float64(width), float64(y)/float64(height)
+			l := 128 + 100*math.Sin(u*fx)*math.Cos(v*fy)
+			img.Set(x, y, color.RGBA{R: uint8(l), G: uint8(l * 0.8), B: uint8(255 - l), A: 255})
+		}
+	}
+	return img
+}
+
+// shrink resizes the image to the size by picking the nearest pixels.
+func shrink(img image.Image, width, height int) *image.RGBA {
+	b := img.Bounds()
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			resized.Set(x, y, img.At(b.Min.X+x*b.Dx()/width, b.Min.Y+y*b.Dy()/height))
+		}
+	}
+	return resized
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image, quality int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decodeImage(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode image: %v", err)
+	}
+	return img
+}
+
+func TestDHash(t *testing.T) {
+	t.Parallel()
+
+	photo := testPhoto(320, 240, 7, 5)
+	original := dHash(photo)
+
+	cases := map[string]struct {
+		image []byte
+
+		wantSimilar bool
+	}{
+		"similar: re-encoded as JPEG": {
+			image:       encodeJPEG(t, photo, 30),
+			wantSimilar: true,
+		},
+		"similar: resized": {
+			image:       encodePNG(t, shrink(photo, 97, 61)),
+			wantSimilar: true,
+		},
+		"different photo": {
+			image: encodePNG(t, testPhoto(320, 240, 11, 3)),
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			distance := hashDistance(original, dHash(decodeImage(t, tt.image)))
+			if similar := distance <= maxSimilarImageDistance; similar != tt.wantSimilar {
+				t.Errorf("expected similar %t, got distance %d", tt.wantSimilar, distance)
+			}
+		})
+	}
+
+	// an image smaller than the hash is hashed as well
+	dHash(shrink(photo, 1, 1))
+}
+
+func TestSimilarItemsE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	h := &Handlers{imgDirPath: t.TempDir(), itemRepo: newTestItemRepository(t, db), imageRepo: NewImageRepository(db)}
+	addItem := func(name string, image []byte) AddItemResponse {
+		t.Helper()
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		writer.WriteField("name", name)
+		writer.WriteField("category", "fashion")
+		part, err := writer.CreateFormFile("image", name+".jpg")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		part.Write(image)
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/items", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set(userIDHeader, "1")
+
+		rr := httptest.NewRecorder()
+		h.AddItem(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		var resp AddItemResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	photo := testPhoto(320, 240, 7, 5)
+	first := addItem("jacket", encodePNG(t, photo))
+	if first.SimilarItemIDs != nil {
+		t.Errorf("expected no similar items for the first listing, got %v", first.SimilarItemIDs)
+	}
+	duplicate := addItem("jacket again", encodeJPEG(t, photo, 40))
+	if diff := cmp.Diff([]int{first.ID}, duplicate.SimilarItemIDs); diff != "" {
+		t.Errorf("unexpected similar items (-want +got):\n%s", diff)
+	}
+	if other := addItem("shirt", encodePNG(t, testPhoto(320, 240, 11, 3))); other.SimilarItemIDs != nil {
+		t.Errorf("expected no similar items for a different image, got %v", other.SimilarItemIDs)
+	}
+
+	getSimilarItems := func(itemID int, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items/"+strconv.Itoa(itemID)+"/similar", nil)
+		if userID != "" {
+			req.Header.Set(userIDHeader, userID)
+		}
+		rr := httptest.NewRecorder()
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /items/{item_id}/similar", h.GetSimilarItems)
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := getSimilarItems(first.ID, "1")
+	var res struct{ Items []Item }
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode items: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].ID != duplicate.ID {
+		t.Errorf("expected the duplicate to be similar, got %s", rr.Body)
+	}
+	// the drafts are not visible to the other users
+	if rr := getSimilarItems(first.ID, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a draft of another user, got %d", http.StatusNotFound, rr.Code)
+	}
+}
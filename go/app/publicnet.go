@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// publicDialTimeout is the timeout to connect to a host on the public internet.
+const publicDialTimeout = 10 * time.Second
+
+// errInternalAddress is returned for a host which is not on the public internet,
+// so that a URL given by a client cannot make the server reach its own network.
+var errInternalAddress = errors.New("the address is not public")
+
+// isPublicAddr reports whether the address is on the public internet,
+// rather than a loopback, private, link-local, multicast or unspecified address.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsUnspecified() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() && !addr.IsMulticast()
+}
+
+// checkPublicHost returns errInternalAddress if the host of a URL is not on the public internet.
+// A host name is resolved, and it must resolve to public addresses only.
+func checkPublicHost(ctx context.Context, host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", errInternalAddress, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublicAddr(addr) {
+			return fmt.Errorf("%w: %s", errInternalAddress, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", errInternalAddress, host, addr)
+		}
+	}
+	return nil
+}
+
+// newPublicHTTPClient returns an HTTP client which connects to the public internet only.
+// The address is checked when connecting, so a host resolving to another address later
+// or a redirect to an internal host is refused as well.
+func newPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: publicDialTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errInternalAddress, address)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// a proxy would be dialed instead of the host, so no proxy is used
+	transport.Proxy = nil
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
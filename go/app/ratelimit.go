@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is the limit of a token bucket: Burst requests at once, refilled at Rate requests per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// defaultRateLimit is applied to the routes not listed in routeRateLimits.
+var defaultRateLimit = RateLimit{Rate: 10, Burst: 20}
+
+// routeRateLimits is the list of limits per route pattern.
+// Uploading an item accepts up to 32MB, so it is much stricter than the others.
+var routeRateLimits = map[string]RateLimit{
+	"POST /items":      {Rate: 0.2, Burst: 5},
+	"POST /items:bulk": {Rate: 0.02, Burst: 2},
+}
+
+// RateLimitResult is the result of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the time until a token is available. It is zero if Allowed is true.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+}
+
+// RateLimitStore is an interface to keep token buckets.
+// The in-memory implementation only works for a single server; a shared store can implement it later.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// memoryRateLimitStore is an in-memory implementation of RateLimitStore
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	// lastSweep is the time idle buckets were removed last.
+	lastSweep time.Time
+}
+
+const (
+	// rateLimitSweepInterval is the interval to remove idle buckets.
+	rateLimitSweepInterval = time.Minute
+	// rateLimitIdleTimeout is the time after which a bucket is removed. It must be longer than
+	// the time to refill any bucket, since only a full bucket is equivalent to a missing one.
+	rateLimitIdleTimeout = 10 * time.Minute
+)
+
+// NewMemoryRateLimitStore creates a new memoryRateLimitStore.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (m *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > rateLimitSweepInterval {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	var res RateLimitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = secondsToDuration((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return res, nil
+}
+
+// sweep removes the buckets which have been idle for rateLimitIdleTimeout.
+func (m *memoryRateLimitStore) sweep(now time.Time) {
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.last) > rateLimitIdleTimeout {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// rateLimitMiddleware limits the requests per client IP and route with token buckets.
+// It sets the RateLimit-* headers and responds 429 Too Many Requests with Retry-After once the bucket is empty.
+func rateLimitMiddleware(mux *http.ServeMux, store RateLimitStore, defaultLimit RateLimit, routeLimits map[string]RateLimit) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		limit, ok := routeLimits[pattern]
+		if !ok {
+			limit = defaultLimit
+		}
+
+		res, err := store.Take(r.Context(), pattern+" "+rateLimitClient(r), limit, time.Now())
+		if err != nil {
+			// do not reject requests only because the store is unavailable
+			slog.Error("failed to take rate limit token", "error", err)
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitClient returns the key of the client making the request, which is the client IP.
+// The X-User-ID header is not authenticated, so a client could get a new bucket with each value of it.
+func rateLimitClient(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Rate: 1, Burst: 2}
+	now := time.Now()
+
+	for i, want := range []bool{true, true, false} {
+		res, err := store.Take(t.Context(), "key", limit, now)
+		if err != nil {
+			t.Fatalf("failed to take token: %v", err)
+		}
+		if res.Allowed != want {
+			t.Errorf("take %d: expected allowed=%v, got %v", i, want, res.Allowed)
+		}
+	}
+
+	res, _ := store.Take(t.Context(), "key", limit, now)
+	if res.RetryAfter != time.Second {
+		t.Errorf("expected retry after 1s, got %s", res.RetryAfter)
+	}
+
+	// a token is refilled after a second
+	res, _ = store.Take(t.Context(), "key", limit, now.Add(time.Second))
+	if !res.Allowed {
+		t.Errorf("expected a token to be refilled")
+	}
+
+	// buckets are independent per key
+	res, _ = store.Take(t.Context(), "other", limit, now)
+	if !res.Allowed || res.Remaining != 1 {
+		t.Errorf("unexpected result for another key: %+v", res)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux.HandleFunc("GET /items", ok)
+	mux.HandleFunc("POST /items", ok)
+	h := rateLimitMiddleware(mux, NewMemoryRateLimitStore(), RateLimit{Rate: 1, Burst: 3}, map[string]RateLimit{
+		"POST /items": {Rate: 1, Burst: 1},
+	})
+
+	doFrom := func(method, userID, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/items", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(userIDHeader, userID)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+	do := func(method, userID string) *httptest.ResponseRecorder {
+		return doFrom(method, userID, "192.0.2.1:1234")
+	}
+
+	if rr := do("POST", ""); rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Limit") != "1" {
+		t.Errorf("unexpected first upload: %d %v", rr.Code, rr.Header())
+	}
+	rr := do("POST", "")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status code %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "1" {
+		t.Errorf("unexpected Retry-After: %q", rr.Header().Get("Retry-After"))
+	}
+
+	// other routes and other IPs have their own buckets
+	if rr := do("GET", ""); rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Remaining") != "2" {
+		t.Errorf("unexpected listing: %d %v", rr.Code, rr.Header())
+	}
+	if rr := doFrom("POST", "", "192.0.2.2:1234"); rr.Code != http.StatusOK {
+		t.Errorf("expected another IP to be allowed, got %d", rr.Code)
+	}
+	// rotating the unauthenticated user ID from an IP does not get a new bucket
+	for _, userID := range []string{"1", "2", "3"} {
+		if rr := do("POST", userID); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("expected user %s from the same IP to be limited, got %d", userID, rr.Code)
+		}
+	}
+}
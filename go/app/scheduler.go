@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// publishSchedulerInterval is the interval to check the scheduled items.
+const publishSchedulerInterval = 30 * time.Second
+
+// runPublishScheduler publishes the scheduled items when their time comes, until ctx is done.
+func runPublishScheduler(ctx context.Context, repo ItemRepository, events *EventBus, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			publishDueItems(ctx, repo, events, now)
+		}
+	}
+}
+
+// publishDueItems publishes the scheduled items due at now and announces them as created.
+func publishDueItems(ctx context.Context, repo ItemRepository, events *EventBus, now time.Time) {
+	ids, err := repo.PublishDueItems(ctx, now)
+	if err != nil {
+		slog.Error("failed to publish scheduled items", "error", err)
+		return
+	}
+	for _, id := range ids {
+		item, err := repo.LoadItem(ctx, id, 0)
+		if err != nil {
+			slog.Error("failed to load published item", "item_id", id, "error", err)
+			continue
+		}
+		slog.Info("published scheduled item", "item_id", id)
+		events.Publish(eventItemCreated, item)
+	}
+}
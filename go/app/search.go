@@ -0,0 +1,94 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// facets of the results of GET /search . Prices are not part of the items yet, so there is no facet of them.
+const (
+	searchFacetCategory = "category"
+	searchFacetStatus   = "status"
+)
+
+// itemStatuses are the statuses accepted by the status filter of GET /search .
+var itemStatuses = []string{itemStatusDraft, itemStatusScheduled, itemStatusPublished, itemStatusPendingReview, itemStatusRejected}
+
+// SearchFilter narrows down the results of a search to the items with any of the values of each facet.
+// A facet without values matches any item.
+type SearchFilter struct {
+	Categories []string
+	Statuses   []string
+}
+
+// FacetCount is the number of the items with a value of a facet.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets are the counts of the values of each facet in the results of a search, the most frequent first.
+// The counts of a facet are filtered by the other facets but not by itself,
+// so that they tell how many items selecting another value of the facet would add.
+type SearchFacets struct {
+	Category []FacetCount `json:"category"`
+	Status   []FacetCount `json:"status"`
+}
+
+// parseSearchFilter parses the "category" and "status" query parameters, which can be repeated to select many values.
+func parseSearchFilter(r *http.Request) (SearchFilter, error) {
+	query := r.URL.Query()
+	filter := SearchFilter{Categories: query[searchFacetCategory], Statuses: query[searchFacetStatus]}
+	for _, status := range filter.Statuses {
+		if !slices.Contains(itemStatuses, status) {
+			return filter, fmt.Errorf("unknown status: %s", status)
+		}
+	}
+	return filter, nil
+}
+
+// matches reports whether the item matches the filter, ignoring the facet skipped.
+func (f SearchFilter) matches(item *Item, skip string) bool {
+	if skip != searchFacetCategory && len(f.Categories) > 0 && !slices.Contains(f.Categories, item.Category) {
+		return false
+	}
+	if skip != searchFacetStatus && len(f.Statuses) > 0 && !slices.Contains(f.Statuses, item.Status) {
+		return false
+	}
+	return true
+}
+
+// facetSearchResults returns the items matching the filter among the results of a search, and the facets of the results.
+func facetSearchResults(items []*Item, filter SearchFilter) ([]*Item, SearchFacets) {
+	filtered := []*Item{}
+	for _, item := range items {
+		if filter.matches(item, "") {
+			filtered = append(filtered, item)
+		}
+	}
+	facets := SearchFacets{
+		Category: countFacet(items, filter, searchFacetCategory, func(item *Item) string { return item.Category }),
+		Status:   countFacet(items, filter, searchFacetStatus, func(item *Item) string { return item.Status }),
+	}
+	return filtered, facets
+}
+
+// countFacet counts the values of the facet among the items matching the other facets of the filter.
+func countFacet(items []*Item, filter SearchFilter, facet string, value func(*Item) string) []FacetCount {
+	counts := map[string]int{}
+	for _, item := range items {
+		if filter.matches(item, facet) {
+			counts[value(item)]++
+		}
+	}
+	facetCounts := make([]FacetCount, 0, len(counts))
+	for v, n := range counts {
+		facetCounts = append(facetCounts, FacetCount{Value: v, Count: n})
+	}
+	slices.SortFunc(facetCounts, func(a, b FacetCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value))
+	})
+	return facetCounts
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFacetSearchResults(t *testing.T) {
+	t.Parallel()
+
+	items := []*Item{
+		{ID: 1, Category: "fashion", Status: itemStatusPublished},
+		{ID: 2, Category: "fashion", Status: itemStatusDraft},
+		{ID: 3, Category: "phone", Status: itemStatusPublished},
+		{ID: 4, Category: "books", Status: itemStatusPublished},
+		{ID: 5, Category: "books", Status: itemStatusPublished},
+	}
+
+	cases := map[string]struct {
+		filter SearchFilter
+
+		wantIDs    []int
+		wantFacets SearchFacets
+	}{
+		"no filter": {
+			wantIDs: []int{1, 2, 3, 4, 5},
+			wantFacets: SearchFacets{
+				Category: []FacetCount{{"books", 2}, {"fashion", 2}, {"phone", 1}},
+				Status:   []FacetCount{{itemStatusPublished, 4}, {itemStatusDraft, 1}},
+			},
+		},
+		"categories": {
+			filter:  SearchFilter{Categories: []string{"fashion", "phone"}},
+			wantIDs: []int{1, 2, 3},
+			// the category facet still counts the other categories
+			wantFacets: SearchFacets{
+				Category: []FacetCount{{"books", 2}, {"fashion", 2}, {"phone", 1}},
+				Status:   []FacetCount{{itemStatusPublished, 2}, {itemStatusDraft, 1}},
+			},
+		},
+		"category and status": {
+			filter:  SearchFilter{Categories: []string{"fashion"}, Statuses: []string{itemStatusDraft}},
+			wantIDs: []int{2},
+			wantFacets: SearchFacets{
+				Category: []FacetCount{{"fashion", 1}},
+				Status:   []FacetCount{{itemStatusDraft, 1}, {itemStatusPublished, 1}},
+			},
+		},
+		"no match": {
+			filter:  SearchFilter{Categories: []string{"toys"}},
+			wantIDs: []int{},
+			wantFacets: SearchFacets{
+				Category: []FacetCount{{"books", 2}, {"fashion", 2}, {"phone", 1}},
+				Status:   []FacetCount{},
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			filtered, facets := facetSearchResults(items, tt.filter)
+			ids := []int{}
+			for _, item := range filtered {
+				ids = append(ids, item.ID)
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("unexpected items (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantFacets, facets); diff != "" {
+				t.Errorf("unexpected facets (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query string
+
+		wantCode  int
+		wantItems int
+	}{
+		"ok: facets without a filter": {
+			query:     "keyword=jacket",
+			wantCode:  http.StatusOK,
+			wantItems: 3,
+		},
+		"ok: drill into categories": {
+			query:     "keyword=jacket&category=fashion&category=outdoor",
+			wantCode:  http.StatusOK,
+			wantItems: 2,
+		},
+		"ng: unknown status": {
+			query:    "keyword=jacket&status=sold",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			if tt.wantCode == http.StatusOK {
+				mockIR.EXPECT().SearchItems(gomock.Any(), "jacket", 0).Return([]*Item{
+					{ID: 1, Name: "jacket", Category: "fashion", Status: itemStatusPublished},
+					{ID: 2, Name: "down jacket", Category: "outdoor", Status: itemStatusPublished},
+					{ID: 3, Name: "jacket book", Category: "books", Status: itemStatusPublished},
+				}, nil)
+			}
+			h := &Handlers{itemRepo: mockIR}
+
+			rr := httptest.NewRecorder()
+			h.Search(rr, httptest.NewRequest(http.MethodGet, "/search?"+tt.query, nil))
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, rr.Code, rr.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var res struct {
+				Items  []Item
+				Facets SearchFacets
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(res.Items) != tt.wantItems {
+				t.Errorf("expected %d items, got %d", tt.wantItems, len(res.Items))
+			}
+			if len(res.Facets.Category) != 3 || len(res.Facets.Status) != 1 {
+				t.Errorf("unexpected facets: %+v", res.Facets)
+			}
+		})
+	}
+}
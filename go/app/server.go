@@ -1,25 +1,75 @@
 package app
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Server struct {
 	// Port is the port number to listen on.
 	Port string
+	// GRPCPort is the port number to serve the gRPC API on, alongside the HTTP API. It is not served if empty.
+	GRPCPort string
 	// ImageDirPath is the path to the directory storing images.
 	ImageDirPath string
+	// ImageURLMode is either ImageURLModePublic or ImageURLModeSigned.
+	// It defaults to the IMAGE_URL_MODE environment variable, then to ImageURLModePublic.
+	ImageURLMode string
+	// ImageURLSecret is the key to sign the image URLs with in the signed mode.
+	// It defaults to the IMAGE_URL_SECRET environment variable.
+	ImageURLSecret string
+	// AdminToken is the token the admins send in the X-Admin-Token header to use the admin routes.
+	// It defaults to the ADMIN_TOKEN environment variable. The admin routes are disabled if it is empty.
+	AdminToken string
+	// DBPath is the path to the SQLite database file. It defaults to db/mercari.sqlite3.
+	DBPath string
+	// DB is the configuration of the connections to the database.
+	DB DBConfig
+	// ItemCache is the configuration of the cache of the items. The items are not cached by default.
+	ItemCache ItemCacheConfig
+	// TraceExporter is where to export the traces: TraceExporterNone, TraceExporterStdout or TraceExporterOTLP.
+	// It defaults to the TRACE_EXPORTER environment variable, then to TraceExporterNone.
+	TraceExporter string
+	// TraceEndpoint is the endpoint of OTLP, like "localhost:4318".
+	// It defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable.
+	TraceEndpoint string
+	// BackupDir is the directory to write the scheduled backups into every BackupInterval.
+	// The backups are not scheduled if either of them is zero.
+	BackupDir      string
+	BackupInterval time.Duration
+	// BackupKeep is the number of the scheduled backups to keep.
+	BackupKeep int
+	// IdempotencyKeyTTL is how long the responses of POST /items are replayed for the retries with the same Idempotency-Key.
+	// It defaults to 24 hours.
+	IdempotencyKeyTTL time.Duration
+	// Moderation is the configuration of the rules to put the new and updated items in review. No item is reviewed by default.
+	Moderation ModerationConfig
+}
+
+const defaultDBPath = "db/mercari.sqlite3"
+
+// openDB opens the database of the server with a single connection, for the subcommands.
+func (s Server) openDB() (*sql.DB, error) {
+	return openWriteDB(context.Background(), cmp.Or(s.DBPath, defaultDBPath), s.DB)
 }
 
 // Run is a method to start the server.
@@ -34,29 +84,134 @@ func (s Server) Run() int {
 	slog.SetLogLoggerLevel(slog.LevelInfo)
 
 	// set up CORS settings
+	// FRONT_URL is a comma separated list of the origins allowed, which can contain "*" wildcards.
 	frontURL, found := os.LookupEnv("FRONT_URL")
 	if !found {
 		frontURL = "http://localhost:3000"
 	}
+	cors := CORSConfig{
+		AllowedOrigins: strings.Split(frontURL, ","),
+		AllowedHeaders: []string{"Content-Type", userIDHeader, adminTokenHeader, "Last-Event-ID", idempotencyKeyHeader, requestIDHeader},
+		ExposedHeaders: []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", idempotentReplayedHeader, requestIDHeader},
+		MaxAge:         10 * time.Minute,
+	}
+
+	// set up image URL settings
+	imageSigner, err := s.imageURLSigner()
+	if err != nil {
+		slog.Error("invalid image URL settings: ", "error", err)
+		return 1
+	}
+
+	// set up tracing
+	shutdownTracing, err := setupTracing(context.Background(), cmp.Or(s.TraceExporter, os.Getenv("TRACE_EXPORTER")), s.TraceEndpoint)
+	if err != nil {
+		slog.Error("failed to set up tracing: ", "error", err)
+		return 1
+	}
+	defer shutdownTracing(context.Background())
 
 	// STEP 5-1: set up the database connection
+	// the writes and the reads have their own pools, not to make the reads wait for the single writer
+	pools, err := OpenDBPools(context.Background(), cmp.Or(s.DBPath, defaultDBPath), s.DB)
+	if err != nil {
+		slog.Error("failed to open database: ", "error", err)
+		return 1
+	}
+	defer pools.Close()
+	db, readDB := pools.Write, WithReadDB(pools.Read)
 
 	// set up handlers
-	itemRepo := NewItemRepository()
-	h := &Handlers{imgDirPath: s.ImageDirPath, itemRepo: itemRepo}
+	itemRepo, err := NewItemRepository(db, readDB)
+	if err != nil {
+		slog.Error("failed to set up item repository (is the database migrated?): ", "error", err)
+		return 1
+	}
+	defer itemRepo.Close()
+	// the cache is in front of the tracing, so that only the queries to the database are traced
+	itemRepo = NewCachedItemRepository(NewTracedItemRepository(itemRepo), s.ItemCache)
+	likeRepo := NewLikeRepository(db, readDB)
+	userRepo := NewUserRepository(db, readDB)
+	idempotencyKeyRepo := NewIdempotencyKeyRepository(db)
+	moderationRepo := NewCachedModerationRepository(NewModerationRepository(db, readDB), itemRepo)
+	auditLogRepo := NewAuditLogRepository(db, readDB)
+	imageRepo := NewImageRepository(db, readDB)
+	commentRepo := NewCommentRepository(db, readDB)
+	webhookRepo := NewWebhookRepository(db, readDB)
+	events := NewEventBus()
+	adminToken := cmp.Or(s.AdminToken, os.Getenv("ADMIN_TOKEN"))
+	if adminToken == "" {
+		slog.Warn("the admin routes are disabled, since ADMIN_TOKEN is not set")
+	}
+	h := &Handlers{imgDirPath: s.ImageDirPath, itemRepo: itemRepo, likeRepo: likeRepo, userRepo: userRepo, idempotencyKeyRepo: idempotencyKeyRepo, idempotencyTTL: s.IdempotencyKeyTTL, moderationRepo: moderationRepo, auditLogRepo: auditLogRepo, imageRepo: imageRepo, moderation: newItemModeration(s.Moderation.Rules(), moderationRepo), commentRepo: commentRepo, webhookRepo: webhookRepo, events: events, imageSigner: imageSigner, adminToken: adminToken, dbStats: pools.Stats}
+
+	// deliver item events to the webhooks in background
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewWebhookDispatcher(webhookRepo).Run(ctx, events)
+	// publish the scheduled items in background
+	go runPublishScheduler(ctx, itemRepo, events, publishSchedulerInterval)
+	// remove the expired idempotency keys in background
+	go runIdempotencyKeyPurger(ctx, idempotencyKeyRepo, h.idempotencyKeyTTL(), idempotencyKeyPurgeInterval)
+	// back up the database and the images in background
+	if s.BackupDir != "" && s.BackupInterval > 0 {
+		go runBackupScheduler(ctx, pools.Read, s.ImageDirPath, s.BackupDir, s.BackupInterval, max(1, s.BackupKeep))
+	}
 
 	// set up routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /", h.Hello)
 	mux.HandleFunc("POST /items", h.AddItem)
+	mux.HandleFunc("POST /items:bulk", h.BulkAddItems)
 	mux.HandleFunc("GET /items", h.GetItems)
+	mux.HandleFunc("GET /items/stream", h.StreamItems)
+	mux.HandleFunc("GET /items/export", h.ExportItems)
 	mux.HandleFunc("GET /items/{item_id}", h.GetItem)
+	mux.HandleFunc("GET /items/{item_id}/similar", h.GetSimilarItems)
+	mux.HandleFunc("PATCH /items/{item_id}", h.UpdateItem)
+	mux.HandleFunc("POST /items/{item_id}/publish", h.PublishItem)
 	mux.HandleFunc("GET /images/{filename}", h.GetImage)
 	mux.HandleFunc("GET /search", h.Search)
+	mux.HandleFunc("POST /items/{item_id}/like", h.LikeItem)
+	mux.HandleFunc("DELETE /items/{item_id}/like", h.UnlikeItem)
+	mux.HandleFunc("GET /likes", h.GetLikedItems)
+	mux.HandleFunc("POST /items/{item_id}/comments", h.AddComment)
+	mux.HandleFunc("GET /items/{item_id}/comments", h.GetComments)
+	mux.HandleFunc("DELETE /comments/{comment_id}", h.DeleteComment)
+	mux.HandleFunc("POST /webhooks", h.AddWebhook)
+	mux.HandleFunc("GET /webhooks", h.GetWebhooks)
+	mux.HandleFunc("DELETE /webhooks/{webhook_id}", h.DeleteWebhook)
+	mux.HandleFunc("GET /webhooks/{webhook_id}/deliveries", h.GetWebhookDeliveries)
+	mux.HandleFunc("GET /debug/db", h.GetDBStats)
+	mux.HandleFunc("GET /graphql", h.GraphQL)
+	mux.HandleFunc("POST /graphql", h.GraphQL)
+	mux.HandleFunc("GET /admin/moderation/items", h.GetItemsInReview)
+	mux.HandleFunc("GET /admin/moderation/items/{item_id}/events", h.GetModerationEvents)
+	mux.HandleFunc("POST /admin/moderation/items/{item_id}/approve", h.ApproveItem)
+	mux.HandleFunc("POST /admin/moderation/items/{item_id}/reject", h.RejectItem)
+	mux.HandleFunc("GET /admin/audit-log", h.GetAuditLog)
+
+	// start the gRPC server
+	if s.GRPCPort != "" {
+		lis, err := net.Listen("tcp", ":"+s.GRPCPort)
+		if err != nil {
+			slog.Error("failed to listen for gRPC: ", "error", err)
+			return 1
+		}
+		grpcServer := newGRPCServer(h)
+		defer grpcServer.Stop()
+		go func() {
+			slog.Info("grpc server started on", "port", s.GRPCPort)
+			if err := grpcServer.Serve(lis); err != nil {
+				slog.Error("failed to serve gRPC: ", "error", err)
+			}
+		}()
+	}
 
 	// start the server
 	slog.Info("http server started on", "port", s.Port)
-	err := http.ListenAndServe(":"+s.Port, simpleCORSMiddleware(simpleLoggerMiddleware(mux), frontURL, []string{"GET", "HEAD", "POST", "OPTIONS"}))
+	limited := rateLimitMiddleware(mux, NewMemoryRateLimitStore(), defaultRateLimit, routeRateLimits)
+	err = http.ListenAndServe(":"+s.Port, tracingMiddleware(corsMiddleware(simpleLoggerMiddleware(auditMiddleware(limited)), mux, cors), mux))
 	if err != nil {
 		slog.Error("failed to start server: ", "error", err)
 		return 1
@@ -65,10 +220,49 @@ func (s Server) Run() int {
 	return 0
 }
 
+// imageURLSigner returns the signer of image URLs, or nil in the public mode.
+func (s Server) imageURLSigner() (*imageURLSigner, error) {
+	mode := cmp.Or(s.ImageURLMode, os.Getenv("IMAGE_URL_MODE"), ImageURLModePublic)
+	secret := cmp.Or(s.ImageURLSecret, os.Getenv("IMAGE_URL_SECRET"))
+	switch mode {
+	case ImageURLModePublic:
+		return nil, nil
+	case ImageURLModeSigned:
+		if secret == "" {
+			return nil, errors.New("a secret is required to sign image URLs")
+		}
+		return newImageURLSigner(secret), nil
+	default:
+		return nil, fmt.Errorf("unknown image URL mode: %s", mode)
+	}
+}
+
 type Handlers struct {
 	// imgDirPath is the path to the directory storing images.
 	imgDirPath string
-	itemRepo   ItemRepository
+	itemRepo    ItemRepository
+	likeRepo    LikeRepository
+	userRepo    UserRepository
+	// idempotencyKeyRepo records the responses of POST /items replayed for the retries with the same Idempotency-Key,
+	// for idempotencyTTL or defaultIdempotencyKeyTTL if it is 0.
+	idempotencyKeyRepo IdempotencyKeyRepository
+	idempotencyTTL     time.Duration
+	moderationRepo     ModerationRepository
+	auditLogRepo       AuditLogRepository
+	// imageRepo records the stored images, to find the listings of similar images. Nil disables it.
+	imageRepo ImageRepository
+	// moderation puts the new and updated items flagged by its rules in review. Nil passes every item.
+	moderation *itemModeration
+	commentRepo CommentRepository
+	webhookRepo WebhookRepository
+	// events publishes item changes to the clients of GET /items/stream.
+	events *EventBus
+	// imageSigner signs the image URLs in the signed mode. It is nil in the public mode.
+	imageSigner *imageURLSigner
+	// adminToken is the token required by the admin routes in the X-Admin-Token header. Empty disables them.
+	adminToken string
+	// dbStats returns the statistics of the database connection pools.
+	dbStats func() map[string]DBPoolStats
 }
 
 type HelloResponse struct {
@@ -93,10 +287,14 @@ type AddItemRequest struct {
 	// Category string `form:"category"` // STEP 4-2: add a category field
 	Category string `form:"category"`
 	Image []byte `form:"image"` // STEP 4-4: add an image field
+	SellerID int // from X-User-ID header
 }
 
+// AddItemResponse is the item added, with a warning of the listings which are likely its duplicates.
 type AddItemResponse struct {
-	Message string `json:"message"`
+	*Item
+	// SimilarItemIDs are the other listings visible to the seller with a similar image, omitted if there are none.
+	SimilarItemIDs []int `json:"similar_item_ids,omitempty"`
 }
 
 // parseAddItemRequest parses and validates the request to add an item.
@@ -105,6 +303,12 @@ func parseAddItemRequest(r *http.Request) (*AddItemRequest, error) {
         return nil, err
     }
 
+    // items are created as drafts, which only their seller can publish
+    sellerID, err := parseUserID(r)
+    if err != nil {
+        return nil, err
+    }
+
     name := r.FormValue("name")
     category := r.FormValue("category")
     file, _, err := r.FormFile("image")
@@ -126,6 +330,7 @@ func parseAddItemRequest(r *http.Request) (*AddItemRequest, error) {
             Name:     name,
             Category: category,
             Image:    nil, // 画像データが空の場合は nil を設定
+            SellerID: sellerID,
         }, nil
     }
 
@@ -138,6 +343,7 @@ func parseAddItemRequest(r *http.Request) (*AddItemRequest, error) {
         Name:     name,
         Category: category,
         Image:    image,
+        SellerID: sellerID,
     }, nil
 }
 
@@ -149,11 +355,22 @@ func (s *Handlers) AddItem(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	// a retry with the same Idempotency-Key gets the response of the first request instead of adding another item
+	key, done := s.reserveIdempotencyKey(w, r, req.SellerID, addItemRequestHash(req))
+	if done {
+		return
+	}
 	// STEP 4-4: uncomment on adding an implementation to store an image
-	fileName, err := s.storeImage(req.Image)
+	fileName, err := s.storeImage(ctx, req.Image)
 	slog.Info("Stored image", "fileName", fileName)
+	if errors.Is(err, errInvalidImage) {
+		s.completeIdempotencyKey(ctx, key, http.StatusBadRequest, nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		slog.Error("failed to store image: ", "error", err)
+		s.completeIdempotencyKey(ctx, key, http.StatusInternalServerError, nil)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -163,23 +380,44 @@ func (s *Handlers) AddItem(w http.ResponseWriter, r *http.Request) {
 		Category: req.Category,
 		// STEP 4-4: add an image field
 		Image: fileName,
+		SellerID: req.SellerID,
+		Status: itemStatusDraft,
 	}
+	// a flagged item is inserted in review, so that it cannot be published before an admin approves it
+	s.moderation.check(item)
 	// データベースに保存
 	err = s.itemRepo.Insert(ctx, item)
 	if err != nil {
     	slog.Error("failed to store item", "error", err)
+		s.completeIdempotencyKey(ctx, key, http.StatusInternalServerError, nil)
     	http.Error(w, "Failed to insert item", http.StatusInternalServerError) // 500を返す
     	return
 	}
+	resp := AddItemResponse{Item: s.imageSigner.withImageURL(item)}
+	// the listing is added anyway, and the seller is warned of the likely duplicates
+	similar, err := s.similarItems(ctx, item, req.SellerID)
+	if err != nil {
+		slog.Error("failed to load similar items", "item_id", item.ID, "error", err)
+	}
+	for _, other := range similar {
+		resp.SimilarItemIDs = append(resp.SimilarItemIDs, other.ID)
+	}
 
 	// レスポンスを送信
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(item)
+	// the response is encoded before writing it, to record it for the retries
+	var body bytes.Buffer
+	err = json.NewEncoder(&body).Encode(resp)
 	if err != nil {
     	slog.Error("failed to encode response", "error", err)
+		s.completeIdempotencyKey(ctx, key, http.StatusInternalServerError, nil)
     	http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.completeIdempotencyKey(ctx, key, http.StatusOK, body.Bytes())
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body.Bytes()); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
 	// // レスポンスボディにもアイテム名とカテゴリを追加
 	// _, err = w.Write([]byte("Item Name: " + req.Name + ", Category: " + req.Category))
 	// if err != nil {
@@ -187,21 +425,203 @@ func (s *Handlers) AddItem(w http.ResponseWriter, r *http.Request) {
 	// 	http.Error(w, err.Error(), http.StatusInternalServerError)
 	// }
 }
+// sseHeartbeatInterval is the interval of comments sent to keep idle SSE connections alive.
+const sseHeartbeatInterval = 15 * time.Second
+
+// StreamItems is a handler to push item events as Server-Sent Events for GET /items/stream .
+// A reconnecting client sends the Last-Event-ID header to receive the events it missed.
+func (s *Handlers) StreamItems(w http.ResponseWriter, r *http.Request) {
+	var lastEventID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastEventID = id
+	}
+
+	rc := http.NewResponseController(w)
+	missed, events, unsubscribe := s.events.Subscribe(lastEventID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Error("streaming is not supported", "error", err)
+		return
+	}
+
+	for _, ev := range missed {
+		if err := s.writeSSE(w, ev); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-events:
+			if err := s.writeSSE(w, ev); err != nil {
+				slog.Debug("failed to write event", "error", err)
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE writes an event in the text/event-stream format.
+func (s *Handlers) writeSSE(w io.Writer, ev ItemEvent) error {
+	ev.Item = s.imageSigner.withImageURL(ev.Item)
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
+
+// maxBulkBodySize is the limit of the body of POST /items:bulk.
+const maxBulkBodySize = 10 << 20
+
+// BulkAddItems is a handler to import items in bulk for POST /items:bulk .
+// The body is CSV or NDJSON, chosen by the format query parameter or the Content-Type header.
+// The items are created as drafts unless the publish query parameter is true.
+// It returns a report of every row, even if some of them failed.
+func (s *Handlers) BulkAddItems(w http.ResponseWriter, r *http.Request) {
+	sellerID, err := parseUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, err := parseBulkFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := itemStatusDraft
+	if r.URL.Query().Get("publish") == "true" {
+		status = itemStatusPublished
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxBulkBodySize)
+	importer := newItemImporter(s.itemRepo, s.storeImage, s.events)
+	importer.moderation = s.moderation
+	report, err := importer.Import(r.Context(), body, format, sellerID, status)
+	if err != nil {
+		// the rows before the error may have been imported, so return the report as well
+		slog.Warn("failed to read bulk import", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "report": report})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// parseBulkFormat returns the format of the bulk import request.
+func parseBulkFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if format != bulkFormatCSV && format != bulkFormatNDJSON {
+			return "", fmt.Errorf("format must be %s or %s", bulkFormatCSV, bulkFormatNDJSON)
+		}
+		return format, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return bulkFormatCSV, nil
+	case "application/x-ndjson", "application/jsonl":
+		return bulkFormatNDJSON, nil
+	default:
+		return "", errors.New("Content-Type must be text/csv or application/x-ndjson")
+	}
+}
+
+// exportFlushRows is the number of rows written between flushes of an export.
+const exportFlushRows = 100
+
+// ExportItems is a handler to export the items as CSV, TSV or NDJSON for GET /items/export?format= .
+// The items are streamed from the database row by row, so that the export does not depend on the memory.
+func (s *Handlers) ExportItems(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := parseOptionalUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := cmp.Or(r.URL.Query().Get("format"), exportFormatCSV)
+	exporter, err := newItemExporter(w, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="items.%s"`, format))
+	rc := http.NewResponseController(w)
+	rows := 0
+	err = s.itemRepo.ForEachItem(r.Context(), viewerID, func(item *Item) error {
+		if err := exporter.Write(item); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows != 0 {
+			return nil
+		}
+		if err := exporter.Flush(); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	if err == nil {
+		err = exporter.Flush()
+	}
+	if err != nil {
+		// the status code has been sent already, so the client only sees a truncated export
+		slog.Error("failed to export items", "rows", rows, "error", err)
+		return
+	}
+}
+
 // GetItems is a handler to return a list of items for GET /items.
+// The drafts of the user are listed along with the published items.
 func (s *Handlers) GetItems(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := parseOptionalUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	ctx := r.Context()
-	items, err := s.itemRepo.LoadItems(ctx)
+	items, err := s.itemRepo.LoadItems(ctx, viewerID)
 	if err != nil {
 		slog.Error("failed to load items: ", "error", err)
 		http.Error(w, "Failed to load items", http.StatusInternalServerError)
 		return
 	}
 	// JSONレスポンスを返す
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	w.Header().Add("Vary", userIDHeader)
+	writeJSONWithETag(w, r, map[string]interface{}{"items": s.imageSigner.withImageURLs(items)})
 }
 
 func (s *Handlers) GetItem(w http.ResponseWriter, r *http.Request) {
@@ -213,35 +633,233 @@ func (s *Handlers) GetItem(w http.ResponseWriter, r *http.Request) {
         return
     }
     slog.Info("Received item_id:", "item_id", itemID)
+	viewerID, err := parseOptionalUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	//2. itemを取得
 	ctx := r.Context()
-	items, err := s.itemRepo.LoadItems(ctx)
+	item, err := s.itemRepo.LoadItem(ctx, itemID, viewerID)
+	if errors.Is(err, errItemNotFound) {
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		slog.Error("failed to load items: ", "error", err)
-		http.Error(w, "Failed to load items", http.StatusInternalServerError)
+		slog.Error("failed to load item: ", "error", err)
+		http.Error(w, "Failed to load item", http.StatusInternalServerError)
 		return
 	}
-	if itemID <= 0 || itemID > len(items) {//配列の範囲外チェック
-        http.Error(w, "Item not found", http.StatusNotFound)
-        return
-    }
-	// leni := len(items)
-	// slog.Info("items_length", "length", leni)
-	item := items[itemID-1]
 	// JSONレスポンスを返す
+	w.Header().Add("Vary", userIDHeader)
+	writeJSONWithETag(w, r, s.imageSigner.withImageURL(item))
+}
+
+type UpdateItemRequest struct {
+	SellerID int    // from X-User-ID header
+	ItemID   int    // path value
+	Name     string `form:"name"`     // unchanged if empty
+	Category string `form:"category"` // unchanged if empty
+}
+
+// parseUpdateItemRequest parses and validates the request to update an item.
+func parseUpdateItemRequest(r *http.Request) (*UpdateItemRequest, error) {
+	sellerID, err := parseUserID(r)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := strconv.Atoi(r.PathValue("item_id"))
+	if err != nil || itemID <= 0 {
+		return nil, errors.New("invalid item ID")
+	}
+	req := &UpdateItemRequest{
+		SellerID: sellerID,
+		ItemID:   itemID,
+		Name:     r.FormValue("name"),
+		Category: r.FormValue("category"),
+	}
+	if req.Name == "" && req.Category == "" {
+		return nil, errors.New("name or category is required")
+	}
+	return req, nil
+}
+
+// UpdateItem is a handler to edit an item of the user for PATCH /items/{item_id} .
+func (s *Handlers) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	req, err := parseUpdateItemRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	item, err := s.itemRepo.LoadItem(ctx, req.ItemID, req.SellerID)
+	if err == nil && item.SellerID != req.SellerID {
+		err = errItemNotFound
+	}
+	if errors.Is(err, errItemNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load item", "error", err)
+		http.Error(w, "failed to load item", http.StatusInternalServerError)
+		return
+	}
+
+	previousStatus := item.Status
+	item.Name = cmp.Or(req.Name, item.Name)
+	item.Category = cmp.Or(req.Category, item.Category)
+	// a flagged item is put in review before the update, so that the new name is never published
+	if reasons := s.moderation.check(item); len(reasons) > 0 {
+		if err := s.moderation.flag(ctx, item.ID, reasons); err != nil {
+			slog.Error("failed to flag item", "error", err)
+			http.Error(w, "failed to update item", http.StatusInternalServerError)
+			return
+		}
+	}
+	err = s.itemRepo.Update(ctx, item)
+	if errors.Is(err, errItemNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to update item", "error", err)
+		http.Error(w, "failed to update item", http.StatusInternalServerError)
+		return
+	}
+	if item.Status != previousStatus {
+		s.events.PublishStatusChange(item)
+	} else if item.Status == itemStatusPublished {
+		s.events.Publish(eventItemUpdated, item)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(item)
+	err = json.NewEncoder(w).Encode(s.imageSigner.withImageURL(item))
+	if err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+type PublishItemRequest struct {
+	SellerID  int       // from X-User-ID header
+	ItemID    int       // path value
+	PublishAt time.Time `form:"publish_at"` // RFC 3339, now if not given
+}
+
+// parsePublishItemRequest parses and validates the request to publish an item.
+func parsePublishItemRequest(r *http.Request) (*PublishItemRequest, error) {
+	sellerID, err := parseUserID(r)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := strconv.Atoi(r.PathValue("item_id"))
+	if err != nil || itemID <= 0 {
+		return nil, errors.New("invalid item ID")
+	}
+	req := &PublishItemRequest{SellerID: sellerID, ItemID: itemID, PublishAt: time.Now()}
+	if v := r.FormValue("publish_at"); v != "" {
+		req.PublishAt, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.New("publish_at must be in RFC 3339 format")
+		}
+	}
+	return req, nil
+}
+
+// PublishItem is a handler to publish a draft of the user for POST /items/{item_id}/publish .
+// If publish_at is in the future, the item is scheduled and published by the scheduler at that time.
+func (s *Handlers) PublishItem(w http.ResponseWriter, r *http.Request) {
+	req, err := parsePublishItemRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	err = s.itemRepo.Publish(ctx, req.ItemID, req.SellerID, req.PublishAt)
+	if errors.Is(err, errItemNotFound) {
+		http.Error(w, "draft not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to publish item", "error", err)
+		http.Error(w, "failed to publish item", http.StatusInternalServerError)
+		return
+	}
+
+	item, err := s.itemRepo.LoadItem(ctx, req.ItemID, req.SellerID)
+	if err != nil {
+		slog.Error("failed to load item", "error", err)
+		http.Error(w, "failed to load item", http.StatusInternalServerError)
+		return
+	}
+	if item.Status == itemStatusPublished {
+		s.events.Publish(eventItemCreated, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(s.imageSigner.withImageURL(item))
+	if err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// writeJSONWithETag writes v as JSON with an ETag computed from the data.
+// The client has to revalidate every time, and gets 304 Not Modified while the data is unchanged.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v any) {
+	body, err := json.Marshal(v)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+}
+
+// etagMatches reports whether the If-None-Match header matches the etag, using the weak comparison.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
 
 // storeImage stores an image and returns the file path and an error if any.
-// this method calculates the hash sum of the image as a file name to avoid the duplication of a same file
-// and stores it in the image directory.
-func (s *Handlers) storeImage(image []byte) (filePath string, err error) {
+// The upload is re-encoded by sanitizeImage not to store its metadata, and an upload which is not an image
+// is an errInvalidImage. An empty upload is stored as it is, as the image of the items without one.
+// this method calculates the hash sum of the sanitized image as a file name to avoid the duplication of a same file
+// and stores it in the image directory, with its dimensions and perceptual hash to find the same photo re-encoded.
+func (s *Handlers) storeImage(ctx context.Context, image []byte) (filePath string, err error) {
+	var sanitized *sanitizedImage
+	if len(image) > 0 {
+		sanitized, err = sanitizeImage(image)
+		if err != nil {
+			return "", err
+		}
+		image = sanitized.Data
+	}
+
 	// STEP 4-4: add an implementation to store an image
 	// - calc hash sum
 	hasher := sha256.New()
@@ -252,9 +870,12 @@ func (s *Handlers) storeImage(image []byte) (filePath string, err error) {
 	// fmt.Println("Generated fileName:", fileName)
 
 	filePath = filepath.Join(s.imgDirPath, fileName)
+	if sanitized != nil {
+		s.saveImage(ctx, fileName, sanitized.Image)
+	}
 	// - check if the image already exists
 	if _, err := os.Stat(filePath); err == nil {
-		return filePath, nil
+		return fileName, nil
 	}
 	// - store image
 	file, err := os.Create(filePath)
@@ -299,6 +920,16 @@ func (s *Handlers) GetImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// in the signed mode, only the URLs issued by the API are valid until they expire
+	var signedFor time.Duration
+	if s.imageSigner != nil {
+		signedFor, err = s.imageSigner.Verify(req.FileName, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
 	imgPath, err := s.buildImagePath(req.FileName)
 	if err != nil {
 		if !errors.Is(err, errImageNotFound) {
@@ -312,10 +943,43 @@ func (s *Handlers) GetImage(w http.ResponseWriter, r *http.Request) {
 		imgPath = filepath.Join(s.imgDirPath, "default.jpg")
 	}
 
+	// the names of stored images are the hash of their content, so they never change.
+	// the default image is returned for any missing name, so it must not be cached as the image of that name.
+	// signed images must not be kept in shared caches nor beyond the expiry of the URL.
+	if hash, ok := imageHash(filepath.Base(imgPath)); ok {
+		if s.imageSigner != nil {
+			w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(signedFor.Seconds())))
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		}
+		w.Header().Set("ETag", `"`+hash+`"`)
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
 	slog.Info("returned image", "path", imgPath)
 	http.ServeFile(w, r, imgPath)
 }
 
+// imageHash returns the sha256 hash in the name of an image stored by storeImage.
+func imageHash(fileName string) (string, bool) {
+	hash := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	if len(hash) != sha256.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+	return hash, true
+}
+
+// isStoredImageName reports whether the file name is the one of an image stored by storeImage,
+// which is the SHA-256 hash of the image with the .jpg extension.
+func isStoredImageName(fileName string) bool {
+	_, ok := imageHash(fileName)
+	return ok && filepath.Ext(fileName) == ".jpg"
+}
+
 // buildImagePath builds the image path and validates it.
 func (s *Handlers) buildImagePath(imageFileName string) (string, error) {
 	imgPath := filepath.Join(s.imgDirPath, filepath.Clean(imageFileName))
@@ -341,6 +1005,8 @@ func (s *Handlers) buildImagePath(imageFileName string) (string, error) {
 }
 
 // 指定されたキーワードを含む商品を検索するエンドポイント
+// Search is a handler to search the items by a keyword for GET /search , with the facets of the results.
+// The "category" and "status" query parameters filter the results by the facets.
 func (s *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 	// クエリパラメータから keyword を取得
 	keyword := r.URL.Query().Get("keyword")
@@ -349,14 +1015,435 @@ func (s *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	viewerID, err := parseOptionalUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := parseSearchFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// データベースで商品を検索
-	items, err := s.itemRepo.SearchItems(r.Context(), keyword)
+	items, err := s.itemRepo.SearchItems(r.Context(), keyword, viewerID)
 	if err != nil {
 		http.Error(w, "failed to search items", http.StatusInternalServerError)
 		return
 	}
+	// the facets are counted over all the results, to drill into them with the filter
+	items, facets := facetSearchResults(items, filter)
 
 	// JSONレスポンスを返す
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": s.imageSigner.withImageURLs(items), "facets": facets})
+}
+
+// userIDHeader is the header carrying the ID of the user making the request.
+// There is no authentication yet, so the client is trusted to send its own user ID.
+const userIDHeader = "X-User-ID"
+
+// parseUserID returns the ID of the user making the request.
+func parseUserID(r *http.Request) (int, error) {
+	userID, err := strconv.Atoi(r.Header.Get(userIDHeader))
+	if err != nil || userID <= 0 {
+		return 0, fmt.Errorf("valid %s header is required", userIDHeader)
+	}
+	return userID, nil
+}
+
+// parseOptionalUserID returns the ID of the user making the request, or 0 for an anonymous request.
+func parseOptionalUserID(r *http.Request) (int, error) {
+	if r.Header.Get(userIDHeader) == "" {
+		return 0, nil
+	}
+	return parseUserID(r)
+}
+
+type LikeRequest struct {
+	UserID int // from X-User-ID header
+	ItemID int // path value
+}
+
+// parseLikeRequest parses and validates the request to like or unlike an item.
+func parseLikeRequest(r *http.Request) (*LikeRequest, error) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := strconv.Atoi(r.PathValue("item_id"))
+	if err != nil || itemID <= 0 {
+		return nil, errors.New("invalid item ID")
+	}
+	return &LikeRequest{UserID: userID, ItemID: itemID}, nil
+}
+
+// LikeItem is a handler to like an item for POST /items/{item_id}/like .
+func (s *Handlers) LikeItem(w http.ResponseWriter, r *http.Request) {
+	req, err := parseLikeRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.likeRepo.Like(r.Context(), req.UserID, req.ItemID)
+	switch {
+	case errors.Is(err, errItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errAlreadyLiked):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		slog.Error("failed to like item", "error", err)
+		http.Error(w, "failed to like item", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlikeItem is a handler to remove a like from an item for DELETE /items/{item_id}/like .
+func (s *Handlers) UnlikeItem(w http.ResponseWriter, r *http.Request) {
+	req, err := parseLikeRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.likeRepo.Unlike(r.Context(), req.UserID, req.ItemID)
+	switch {
+	case errors.Is(err, errLikeNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("failed to unlike item", "error", err)
+		http.Error(w, "failed to unlike item", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetLikedItems is a handler to return the items liked by the user for GET /likes .
+func (s *Handlers) GetLikedItems(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := s.likeRepo.LoadLikedItems(r.Context(), userID)
+	if err != nil {
+		slog.Error("failed to load liked items", "error", err)
+		http.Error(w, "failed to load liked items", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]interface{}{"items": s.imageSigner.withImageURLs(items)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+const (
+	defaultCommentsLimit = 20
+	maxCommentsLimit     = 100
+)
+
+type AddCommentRequest struct {
+	UserID int    // from X-User-ID header
+	ItemID int    // path value
+	Body   string `form:"body"`
+}
+
+// parseAddCommentRequest parses and validates the request to add a comment.
+func parseAddCommentRequest(r *http.Request) (*AddCommentRequest, error) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := strconv.Atoi(r.PathValue("item_id"))
+	if err != nil || itemID <= 0 {
+		return nil, errors.New("invalid item ID")
+	}
+	body := strings.TrimSpace(r.FormValue("body"))
+	if body == "" {
+		return nil, errors.New("body is required")
+	}
+	return &AddCommentRequest{UserID: userID, ItemID: itemID, Body: body}, nil
+}
+
+// AddComment is a handler to add a comment on an item for POST /items/{item_id}/comments .
+func (s *Handlers) AddComment(w http.ResponseWriter, r *http.Request) {
+	req, err := parseAddCommentRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment := &Comment{ItemID: req.ItemID, UserID: req.UserID, Body: req.Body}
+	err = s.commentRepo.Insert(r.Context(), comment)
+	if errors.Is(err, errItemNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to insert comment", "error", err)
+		http.Error(w, "failed to insert comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(comment)
+	if err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+type GetCommentsRequest struct {
+	ItemID int // path value
+	Limit  int // query parameter
+	Offset int // query parameter
+}
+
+// parseGetCommentsRequest parses and validates the request to list comments.
+func parseGetCommentsRequest(r *http.Request) (*GetCommentsRequest, error) {
+	itemID, err := strconv.Atoi(r.PathValue("item_id"))
+	if err != nil || itemID <= 0 {
+		return nil, errors.New("invalid item ID")
+	}
+	req := &GetCommentsRequest{ItemID: itemID, Limit: defaultCommentsLimit}
+
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		req.Limit, err = strconv.Atoi(v)
+		if err != nil || req.Limit <= 0 || req.Limit > maxCommentsLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxCommentsLimit)
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		req.Offset, err = strconv.Atoi(v)
+		if err != nil || req.Offset < 0 {
+			return nil, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return req, nil
+}
+
+type GetCommentsResponse struct {
+	Comments []*Comment `json:"comments"`
+	// NextOffset is the offset of the next page, or omitted if this is the last page.
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// GetComments is a handler to return a page of comments on an item for GET /items/{item_id}/comments .
+func (s *Handlers) GetComments(w http.ResponseWriter, r *http.Request) {
+	req, err := parseGetCommentsRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// fetch one extra comment to find out whether there is a next page
+	comments, err := s.commentRepo.LoadComments(r.Context(), req.ItemID, req.Limit+1, req.Offset)
+	if err != nil {
+		slog.Error("failed to load comments", "error", err)
+		http.Error(w, "failed to load comments", http.StatusInternalServerError)
+		return
+	}
+
+	resp := GetCommentsResponse{Comments: comments}
+	if len(comments) > req.Limit {
+		resp.Comments = comments[:req.Limit]
+		next := req.Offset + req.Limit
+		resp.NextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DeleteComment is a handler to delete the user's own comment for DELETE /comments/{comment_id} .
+func (s *Handlers) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	commentID, err := strconv.Atoi(r.PathValue("comment_id"))
+	if err != nil || commentID <= 0 {
+		http.Error(w, "invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.commentRepo.Delete(r.Context(), commentID, userID)
+	if errors.Is(err, errCommentNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to delete comment", "error", err)
+		http.Error(w, "failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type AddWebhookRequest struct {
+	URL    string   `form:"url"`
+	Secret string   `form:"secret"`
+	Events []string `form:"events"` // comma separated
+}
+
+// parseAddWebhookRequest parses and validates the request to add a webhook.
+func parseAddWebhookRequest(r *http.Request) (*AddWebhookRequest, error) {
+	req := &AddWebhookRequest{
+		URL:    r.FormValue("url"),
+		Secret: r.FormValue("secret"),
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("url must be an absolute http or https URL")
+	}
+	// the server must not be made to post to its own network
+	if err := checkPublicHost(r.Context(), u.Hostname()); err != nil {
+		return nil, fmt.Errorf("url must be on the public internet: %w", err)
+	}
+	if v := r.FormValue("events"); v != "" {
+		req.Events = strings.Split(v, ",")
+	}
+	if !validWebhookEvents(req.Events) {
+		return nil, fmt.Errorf("events must be a comma separated list of %s", strings.Join(webhookEventTypes, ", "))
+	}
+	return req, nil
+}
+
+type AddWebhookResponse struct {
+	*Webhook
+	// Secret is returned only once, so that the receiver can verify the signatures.
+	Secret string `json:"secret"`
+}
+
+// AddWebhook is a handler to subscribe a webhook to item events for POST /webhooks .
+// If no secret is given, a random one is generated. The webhook routes are for the admins only.
+func (s *Handlers) AddWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	req, err := parseAddWebhookRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hook := &Webhook{URL: req.URL, Secret: req.Secret, Events: req.Events}
+	if hook.Secret == "" {
+		hook.Secret = rand.Text()
+	}
+	err = s.webhookRepo.InsertWebhook(r.Context(), hook)
+	if err != nil {
+		slog.Error("failed to insert webhook", "error", err)
+		http.Error(w, "failed to insert webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(AddWebhookResponse{Webhook: hook, Secret: hook.Secret})
+	if err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// GetWebhooks is a handler to return the webhook subscriptions for GET /webhooks .
+func (s *Handlers) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	hooks, err := s.webhookRepo.LoadWebhooks(r.Context())
+	if err != nil {
+		slog.Error("failed to load webhooks", "error", err)
+		http.Error(w, "failed to load webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]interface{}{"webhooks": hooks})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DeleteWebhook is a handler to unsubscribe a webhook for DELETE /webhooks/{webhook_id} .
+func (s *Handlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	webhookID, err := strconv.Atoi(r.PathValue("webhook_id"))
+	if err != nil || webhookID <= 0 {
+		http.Error(w, "invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.webhookRepo.DeleteWebhook(r.Context(), webhookID)
+	if errors.Is(err, errWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to delete webhook", "error", err)
+		http.Error(w, "failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries is a handler to return the delivery log of a webhook for GET /webhooks/{webhook_id}/deliveries .
+func (s *Handlers) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	webhookID, err := strconv.Atoi(r.PathValue("webhook_id"))
+	if err != nil || webhookID <= 0 {
+		http.Error(w, "invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := s.webhookRepo.LoadDeliveries(r.Context(), webhookID)
+	if err != nil {
+		slog.Error("failed to load webhook deliveries", "error", err)
+		http.Error(w, "failed to load webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]interface{}{"deliveries": deliveries})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetDBStats is a handler to return the statistics of the database connection pools for GET /debug/db,
+// to monitor whether the requests wait for the connections. It is for the admins only.
+func (s *Handlers) GetDBStats(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	if s.dbStats == nil {
+		http.Error(w, "database statistics are not available", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"pools": s.dbStats()}); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
@@ -37,9 +39,11 @@ func TestParseAddItemRequest(t *testing.T) {
 	cases := map[string]struct {
 		args map[string]string
 		ImageData []byte
+		userID string
 		wants
 	}{
 		"ok: valid request": {
+			userID: "1",
 			args: map[string]string{
 				"name":     "jacket", // fill here
 				"category": "fashion", // fill here
@@ -51,11 +55,13 @@ func TestParseAddItemRequest(t *testing.T) {
 					Name: "jacket", // fill here
 					Category: "fashion", // fill here
 					Image: ImageBytes,
+					SellerID: 1,
 				},
 				err: false,
 			},
 		},
 		"ng: empty request": {
+			userID: "1",
 			args: map[string]string{},
 			ImageData:	nil,
 			wants: wants{
@@ -64,6 +70,7 @@ func TestParseAddItemRequest(t *testing.T) {
 			},
 		},
 		"ng: empty name": {
+			userID: "1",
 			args:		map[string]string{
 				"category": "fashion",
 				"image":    "default.jpg",
@@ -76,6 +83,7 @@ func TestParseAddItemRequest(t *testing.T) {
 		},
 
 		"ng: empty category": {
+			userID: "1",
 			args:		map[string]string{
 				"name":		"jacket",
 				"image":	"default.jpg",
@@ -88,6 +96,7 @@ func TestParseAddItemRequest(t *testing.T) {
 		},
 
 		"ng: empty image": {
+			userID: "1",
 			args:		map[string]string{
 				"name":     "jacket",
 				"category": "fashion",
@@ -97,10 +106,23 @@ func TestParseAddItemRequest(t *testing.T) {
 				req: &AddItemRequest{
                     Name:     "jacket",
                     Category: "fashion",
+                    SellerID: 1,
                 },
                 err: false,
 			},
 		},
+
+		"ng: missing user": {
+			args:		map[string]string{
+				"name":     "jacket",
+				"category": "fashion",
+			},
+			ImageData:	ImageBytes,
+			wants: wants{
+				req: nil,
+				err: true,
+			},
+		},
 	}
 
 	for name, tt := range cases {
@@ -124,6 +146,7 @@ func TestParseAddItemRequest(t *testing.T) {
 
 			req := httptest.NewRequest("POST", "/items", &buf)
 			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.Header.Set(userIDHeader, tt.userID)
 			// execute test target
 			got, err := parseAddItemRequest(req)
 
@@ -204,7 +227,7 @@ func TestAddItem(t *testing.T) {
 			},
 			wants: wants{
 				code: http.StatusOK,
-				body: `{"name":"used iPhone 16e","category":"phone","image":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855.jpg"}` + "\n",
+				body: `{"id":0,"name":"used iPhone 16e","category":"phone","image":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855.jpg","seller_id":1,"status":"draft","like_count":0,"comment_count":0}` + "\n",
 			},
 		},
 		"ng: failed to insert": {
@@ -256,6 +279,7 @@ func TestAddItem(t *testing.T) {
 			writer.Close()
 			req := httptest.NewRequest("POST", "/items", &buf)
 			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.Header.Set(userIDHeader, "1")
 			rr := httptest.NewRecorder()
 
 			h.AddItem(rr, req)
@@ -279,6 +303,519 @@ func TestAddItem(t *testing.T) {
 	}
 }
 
+func TestGetItemsETag(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	mockIR.EXPECT().LoadItems(gomock.Any(), 0).Return([]*Item{{ID: 1, Name: "jacket", Category: "fashion"}}, nil).Times(3)
+	h := &Handlers{itemRepo: mockIR}
+
+	rr := httptest.NewRecorder()
+	h.GetItems(rr, httptest.NewRequest("GET", "/items", nil))
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %d %q", rr.Code, etag)
+	}
+
+	req := httptest.NewRequest("GET", "/items", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	h.GetItems(rr, req)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("expected 304 without body, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/items", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	rr = httptest.NewRecorder()
+	h.GetItems(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 for a stale ETag, got %d", rr.Code)
+	}
+}
+
+func TestGetItemsVary(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	mockIR.EXPECT().LoadItems(gomock.Any(), 0).Return([]*Item{{ID: 1, Name: "jacket"}}, nil)
+	mockIR.EXPECT().LoadItem(gomock.Any(), 1, 0).Return(&Item{ID: 1, Name: "jacket"}, nil)
+	h := &Handlers{itemRepo: mockIR}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items", h.GetItems)
+	mux.HandleFunc("GET /items/{item_id}", h.GetItem)
+	handler := corsMiddleware(mux, mux, CORSConfig{AllowedOrigins: []string{"http://localhost:3000"}})
+
+	// the responses differ by both the origin and the viewer, so a cache must not share them across either
+	for _, target := range []string{"/items", "/items/1"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Origin", "http://localhost:3000")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if diff := cmp.Diff([]string{"Origin", userIDHeader}, rr.Header().Values("Vary")); diff != "" {
+			t.Errorf("%s: unexpected Vary (-want +got):\n%s", target, diff)
+		}
+	}
+}
+
+func TestExportItems(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	mockIR.EXPECT().ForEachItem(gomock.Any(), 0, gomock.Any()).DoAndReturn(func(_ any, _ int, fn func(*Item) error) error {
+		for _, item := range []*Item{{ID: 1, Name: "jacket"}, {ID: 2, Name: "shoes"}} {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	h := &Handlers{itemRepo: mockIR}
+
+	rr := httptest.NewRecorder()
+	h.ExportItems(rr, httptest.NewRequest("GET", "/items/export?format=ndjson", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+	if lines := strings.Count(rr.Body.String(), "\n"); lines != 2 {
+		t.Errorf("expected 2 lines, got %d: %s", lines, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.ExportItems(rr, httptest.NewRequest("GET", "/items/export?format=xml", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestGetImageCacheControl(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hashed := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855.jpg"
+	for _, name := range []string{hashed, "default.jpg"} {
+		if err := os.WriteFile(dir+"/"+name, []byte("image"), 0644); err != nil {
+			t.Fatalf("failed to write image: %v", err)
+		}
+	}
+	h := &Handlers{imgDirPath: dir}
+
+	cases := map[string]struct {
+		fileName string
+		want     string
+	}{
+		"hashed image is immutable":    {fileName: hashed, want: "public, max-age=31536000, immutable"},
+		"default image is revalidated": {fileName: "missing.jpg", want: "no-cache"},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/images/"+tt.fileName, nil)
+			req.SetPathValue("filename", tt.fileName)
+			rr := httptest.NewRecorder()
+			h.GetImage(rr, req)
+
+			if got := rr.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("expected Cache-Control %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStreamItems(t *testing.T) {
+	t.Parallel()
+
+	bus := NewEventBus()
+	bus.Publish(eventItemCreated, &Item{ID: 1, Name: "jacket"})
+	h := &Handlers{events: bus}
+	srv := httptest.NewServer(http.HandlerFunc(h.StreamItems))
+	t.Cleanup(srv.Close)
+
+	req, err := http.NewRequestWithContext(t.Context(), "GET", srv.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Last-Event-ID", "0")
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+
+	// the handler has subscribed once the headers are flushed
+	bus.Publish(eventItemCreated, &Item{ID: 2, Name: "shoes"})
+
+	want := "id: 2\nevent: item.created\ndata: "
+	buf := make([]byte, len(want))
+	if _, err := io.ReadFull(res.Body, buf); err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+	if string(buf) != want {
+		t.Errorf("unexpected event: %q", buf)
+	}
+}
+
+func TestLikeItem(t *testing.T) {
+	t.Parallel()
+
+	type wants struct {
+		code int
+	}
+	cases := map[string]struct {
+		userID   string
+		itemID   string
+		injector func(m *MockLikeRepository)
+		wants
+	}{
+		"ok: liked": {
+			userID: "1",
+			itemID: "2",
+			injector: func(m *MockLikeRepository) {
+				m.EXPECT().Like(gomock.Any(), 1, 2).Return(nil)
+			},
+			wants: wants{code: http.StatusNoContent},
+		},
+		"ng: missing user": {
+			userID:   "",
+			itemID:   "2",
+			injector: func(m *MockLikeRepository) {},
+			wants:    wants{code: http.StatusBadRequest},
+		},
+		"ng: invalid item ID": {
+			userID:   "1",
+			itemID:   "abc",
+			injector: func(m *MockLikeRepository) {},
+			wants:    wants{code: http.StatusBadRequest},
+		},
+		"ng: item not found": {
+			userID: "1",
+			itemID: "2",
+			injector: func(m *MockLikeRepository) {
+				m.EXPECT().Like(gomock.Any(), 1, 2).Return(errItemNotFound)
+			},
+			wants: wants{code: http.StatusNotFound},
+		},
+		"ng: already liked": {
+			userID: "1",
+			itemID: "2",
+			injector: func(m *MockLikeRepository) {
+				m.EXPECT().Like(gomock.Any(), 1, 2).Return(errAlreadyLiked)
+			},
+			wants: wants{code: http.StatusConflict},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+
+			mockLR := NewMockLikeRepository(ctrl)
+			tt.injector(mockLR)
+			h := &Handlers{likeRepo: mockLR}
+
+			req := httptest.NewRequest("POST", "/items/"+tt.itemID+"/like", nil)
+			req.SetPathValue("item_id", tt.itemID)
+			req.Header.Set(userIDHeader, tt.userID)
+			rr := httptest.NewRecorder()
+
+			h.LikeItem(rr, req)
+
+			if tt.wants.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.wants.code, rr.Code)
+			}
+		})
+	}
+}
+
+func TestDraftE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := t.Context()
+	itemRepo := newTestItemRepository(t, db)
+	draft := &Item{Name: "jacket", Category: "fashion", Image: "default.jpg", SellerID: 10, Status: itemStatusDraft}
+	if err := itemRepo.Insert(ctx, draft); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+
+	countVisible := func(viewerID int) int {
+		t.Helper()
+		items, err := itemRepo.LoadItems(ctx, viewerID)
+		if err != nil {
+			t.Fatalf("failed to load items: %v", err)
+		}
+		return len(items)
+	}
+	if n := countVisible(0); n != 0 {
+		t.Errorf("expected the draft to be hidden from others, got %d items", n)
+	}
+	if n := countVisible(10); n != 1 {
+		t.Errorf("expected the draft to be visible to the seller, got %d items", n)
+	}
+	if _, err := itemRepo.LoadItem(ctx, draft.ID, 20); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound, got %v", err)
+	}
+
+	draft.Name = "warm jacket"
+	draft.SellerID = 20
+	if err := itemRepo.Update(ctx, draft); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected others not to be able to update the draft, got %v", err)
+	}
+	draft.SellerID = 10
+	if err := itemRepo.Update(ctx, draft); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+
+	publishAt := time.Now().Add(time.Hour)
+	if err := itemRepo.Publish(ctx, draft.ID, 20, publishAt); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected others not to be able to publish the draft, got %v", err)
+	}
+	if err := itemRepo.Publish(ctx, draft.ID, 10, publishAt); err != nil {
+		t.Fatalf("failed to publish item: %v", err)
+	}
+	item, err := itemRepo.LoadItem(ctx, draft.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to load item: %v", err)
+	}
+	if item.Status != itemStatusScheduled || item.Name != "warm jacket" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+
+	ids, err := itemRepo.PublishDueItems(ctx, time.Now())
+	if err != nil || len(ids) != 0 {
+		t.Errorf("expected no item to be due yet, got %v, %v", ids, err)
+	}
+	ids, err = itemRepo.PublishDueItems(ctx, publishAt.Add(time.Second))
+	if err != nil {
+		t.Fatalf("failed to publish due items: %v", err)
+	}
+	if diff := cmp.Diff([]int{draft.ID}, ids); diff != "" {
+		t.Errorf("unexpected published items (-want +got):\n%s", diff)
+	}
+	if n := countVisible(0); n != 1 {
+		t.Errorf("expected the published item to be visible, got %d items", n)
+	}
+}
+
+func TestLikeE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := t.Context()
+	itemRepo := newTestItemRepository(t, db)
+	likeRepo := NewLikeRepository(db)
+	if err := itemRepo.Insert(ctx, &Item{Name: "jacket", Category: "fashion", Image: "default.jpg"}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+
+	if err := likeRepo.Like(ctx, 1, 1); err != nil {
+		t.Fatalf("failed to like item: %v", err)
+	}
+	if err := likeRepo.Like(ctx, 1, 1); !errors.Is(err, errAlreadyLiked) {
+		t.Errorf("expected errAlreadyLiked, got %v", err)
+	}
+	if err := likeRepo.Like(ctx, 1, 99); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound, got %v", err)
+	}
+
+	items, err := likeRepo.LoadLikedItems(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to load liked items: %v", err)
+	}
+	if len(items) != 1 || items[0].LikeCount != 1 {
+		t.Errorf("unexpected liked items: %+v", items)
+	}
+
+	// the item leaves the likes once it is put in review
+	if err := NewModerationRepository(db).Flag(ctx, 1, []string{"banned word"}); err != nil {
+		t.Fatalf("failed to flag item: %v", err)
+	}
+	items, err = likeRepo.LoadLikedItems(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to load liked items: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected the item in review not to be among the liked items, got %+v", items)
+	}
+
+	if err := likeRepo.Unlike(ctx, 1, 1); err != nil {
+		t.Fatalf("failed to unlike item: %v", err)
+	}
+	if err := likeRepo.Unlike(ctx, 1, 1); !errors.Is(err, errLikeNotFound) {
+		t.Errorf("expected errLikeNotFound, got %v", err)
+	}
+}
+
+func TestGetComments(t *testing.T) {
+	t.Parallel()
+
+	comments := []*Comment{{ID: 1, Body: "a"}, {ID: 2, Body: "b"}, {ID: 3, Body: "c"}}
+
+	type wants struct {
+		code       int
+		count      int
+		nextOffset *int
+	}
+	next := 2
+	cases := map[string]struct {
+		query    string
+		injector func(m *MockCommentRepository)
+		wants
+	}{
+		"ok: has next page": {
+			query: "?limit=2",
+			injector: func(m *MockCommentRepository) {
+				m.EXPECT().LoadComments(gomock.Any(), 1, 3, 0).Return(comments, nil)
+			},
+			wants: wants{code: http.StatusOK, count: 2, nextOffset: &next},
+		},
+		"ok: last page": {
+			query: "?limit=2&offset=2",
+			injector: func(m *MockCommentRepository) {
+				m.EXPECT().LoadComments(gomock.Any(), 1, 3, 2).Return(comments[2:], nil)
+			},
+			wants: wants{code: http.StatusOK, count: 1},
+		},
+		"ng: limit too large": {
+			query:    "?limit=1000",
+			injector: func(m *MockCommentRepository) {},
+			wants:    wants{code: http.StatusBadRequest},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+
+			mockCR := NewMockCommentRepository(ctrl)
+			tt.injector(mockCR)
+			h := &Handlers{commentRepo: mockCR}
+
+			req := httptest.NewRequest("GET", "/items/1/comments"+tt.query, nil)
+			req.SetPathValue("item_id", "1")
+			rr := httptest.NewRecorder()
+
+			h.GetComments(rr, req)
+
+			if tt.wants.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.wants.code, rr.Code)
+			}
+			if tt.wants.code >= 400 {
+				return
+			}
+
+			var resp GetCommentsResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Comments) != tt.wants.count {
+				t.Errorf("expected %d comments, got %d", tt.wants.count, len(resp.Comments))
+			}
+			if diff := cmp.Diff(tt.wants.nextOffset, resp.NextOffset); diff != "" {
+				t.Errorf("unexpected next offset (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCommentE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := t.Context()
+	itemRepo := newTestItemRepository(t, db)
+	commentRepo := NewCommentRepository(db)
+	if err := itemRepo.Insert(ctx, &Item{Name: "jacket", Category: "fashion", Image: "default.jpg", SellerID: 10}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+
+	question := &Comment{ItemID: 1, UserID: 20, Body: "Is this still available?"}
+	if err := commentRepo.Insert(ctx, question); err != nil {
+		t.Fatalf("failed to insert comment: %v", err)
+	}
+	answer := &Comment{ItemID: 1, UserID: 10, Body: "Yes!"}
+	if err := commentRepo.Insert(ctx, answer); err != nil {
+		t.Fatalf("failed to insert comment: %v", err)
+	}
+	if question.IsSellerReply || !answer.IsSellerReply {
+		t.Errorf("unexpected seller reply flags: question=%v, answer=%v", question.IsSellerReply, answer.IsSellerReply)
+	}
+	if err := commentRepo.Insert(ctx, &Comment{ItemID: 99, UserID: 20, Body: "?"}); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound, got %v", err)
+	}
+
+	items, err := itemRepo.LoadItems(ctx, 0)
+	if err != nil {
+		t.Fatalf("failed to load items: %v", err)
+	}
+	if len(items) != 1 || items[0].CommentCount != 2 {
+		t.Errorf("unexpected items: %+v", items)
+	}
+
+	if err := commentRepo.Delete(ctx, question.ID, 10); !errors.Is(err, errCommentNotFound) {
+		t.Errorf("expected errCommentNotFound when deleting others' comment, got %v", err)
+	}
+	if err := commentRepo.Delete(ctx, question.ID, 20); err != nil {
+		t.Fatalf("failed to delete comment: %v", err)
+	}
+	comments, err := commentRepo.LoadComments(ctx, 1, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to load comments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].ID != answer.ID {
+		t.Errorf("unexpected comments: %+v", comments)
+	}
+}
+
 // STEP 6-4: uncomment this test
 func TestAddItemE2e(t *testing.T) {
 	if testing.Short() {
@@ -324,7 +861,7 @@ func TestAddItemE2e(t *testing.T) {
 
 	for name, tt := range cases {
 		t.Run(name, func(t *testing.T) {
-			h := &Handlers{itemRepo: &itemRepository{db: db}}
+			h := &Handlers{itemRepo: newTestItemRepository(t, db)}
 
 			var buf bytes.Buffer
             writer := multipart.NewWriter(&buf)
@@ -347,6 +884,7 @@ func TestAddItemE2e(t *testing.T) {
 			writer.Close()
             req := httptest.NewRequest("POST", "/items", &buf)
             req.Header.Set("Content-Type", writer.FormDataContentType())
+            req.Header.Set(userIDHeader, "1")
 
 			rr := httptest.NewRecorder()
 			h.AddItem(rr, req)
@@ -369,6 +907,18 @@ func TestAddItemE2e(t *testing.T) {
 	}
 }
 
+// newTestItemRepository creates an itemRepository on the migrated db, closed at the end of the test.
+func newTestItemRepository(t testing.TB, db *sql.DB, opts ...RepositoryOption) ItemRepository {
+	t.Helper()
+
+	repo, err := NewItemRepository(db, opts...)
+	if err != nil {
+		t.Fatalf("failed to create item repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
 func setupDB(t *testing.T) (db *sql.DB, closers []func(), e error) {
 	t.Helper()
 
@@ -388,10 +938,13 @@ func setupDB(t *testing.T) (db *sql.DB, closers []func(), e error) {
 	closers = append(closers, func() {
 		f.Close()
 		os.Remove(f.Name())
+		os.Remove(f.Name() + "-wal")
+		os.Remove(f.Name() + "-shm")
 	})
 
 	// set up tables
-	db, err = sql.Open("sqlite3", f.Name())
+	// open the database with the same pragmas as the server, to enforce the foreign keys in the tests too
+	db, err = openWriteDB(t.Context(), f.Name(), DBConfig{})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -399,27 +952,10 @@ func setupDB(t *testing.T) (db *sql.DB, closers []func(), e error) {
 		db.Close()
 	})
 
-	// TODO: replace it with real SQL statements.
-	cmd := `CREATE TABLE IF NOT EXISTS items (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        name VARCHAR(255),
-        category_id INTEGER,
-        image VARCHAR(255),
-        FOREIGN KEY (category_id) REFERENCES categories(id)
-    )`
-	_, err = db.Exec(cmd)
-	if err != nil {
+	// apply the same migrations as `api migrate`
+	if _, err := migrate(t.Context(), db); err != nil {
 		return nil, nil, err
 	}
-	// categoriesテーブルの作成
-    cmd = `CREATE TABLE IF NOT EXISTS categories (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        name VARCHAR(255) UNIQUE
-    )`
-    _, err = db.Exec(cmd)
-    if err != nil {
-        return nil, nil, err
-    }
 
 	return db, closers, nil
 }
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exporters of the traces
+const (
+	// TraceExporterNone does not record the traces.
+	TraceExporterNone = "none"
+	// TraceExporterStdout writes the traces to the standard output, for local use.
+	TraceExporterStdout = "stdout"
+	// TraceExporterOTLP sends the traces to an OpenTelemetry collector over OTLP/HTTP.
+	TraceExporterOTLP = "otlp"
+)
+
+// tracerName is the name of the instrumentation of this package.
+const tracerName = "mercari-build-training/app"
+
+// setupTracing sets up the global tracer provider exporting the traces with the exporter,
+// and returns the function to flush and stop it.
+// The endpoint of OTLP is like "localhost:4318", which defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable.
+func setupTracing(ctx context.Context, exporter, endpoint string) (func(context.Context) error, error) {
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", TraceExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case TraceExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case TraceExporterOTLP:
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+		}
+		spanExporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q: must be %q, %q or %q", exporter, TraceExporterNone, TraceExporterStdout, TraceExporterOTLP)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName("mercari-build-training")))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// tracingMiddleware starts a span for every request, named after the route of mux handling it.
+// It is the outermost middleware, so that the span covers the whole chain and continues the trace of the client.
+func tracingMiddleware(next http.Handler, mux *http.ServeMux, opts ...otelhttp.Option) http.Handler {
+	opts = append(opts, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return pattern
+		}
+		return r.Method
+	}))
+	return otelhttp.NewHandler(next, "http.server", opts...)
+}
+
+// tracedItemRepository is an ItemRepository recording a span for every query to repo,
+// with the SQL operation and the number of the rows.
+type tracedItemRepository struct {
+	ItemRepository
+	tracer trace.Tracer
+}
+
+// NewTracedItemRepository returns repo recording the spans of its queries with the global tracer provider.
+func NewTracedItemRepository(repo ItemRepository) ItemRepository {
+	return newTracedItemRepository(repo, otel.GetTracerProvider())
+}
+
+func newTracedItemRepository(repo ItemRepository, provider trace.TracerProvider) ItemRepository {
+	return &tracedItemRepository{ItemRepository: repo, tracer: provider.Tracer(tracerName)}
+}
+
+// start starts the span of a query of the operation on the items.
+func (t *tracedItemRepository) start(ctx context.Context, method, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemNameSQLite, semconv.DBOperationName(operation), semconv.DBCollectionName("items"))
+	return t.tracer.Start(ctx, "ItemRepository."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan ends the span with the number of the rows returned or affected by the query, and its error if any.
+func endSpan(span trace.Span, rows int, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(semconv.DBResponseReturnedRows(rows))
+	}
+	span.End()
+}
+
+func (t *tracedItemRepository) Insert(ctx context.Context, item *Item) error {
+	ctx, span := t.start(ctx, "Insert", "INSERT")
+	err := t.ItemRepository.Insert(ctx, item)
+	endSpan(span, 1, err)
+	return err
+}
+
+func (t *tracedItemRepository) LoadItems(ctx context.Context, viewerID int) ([]*Item, error) {
+	ctx, span := t.start(ctx, "LoadItems", "SELECT")
+	items, err := t.ItemRepository.LoadItems(ctx, viewerID)
+	endSpan(span, len(items), err)
+	return items, err
+}
+
+func (t *tracedItemRepository) LoadItem(ctx context.Context, itemID, viewerID int) (*Item, error) {
+	ctx, span := t.start(ctx, "LoadItem", "SELECT", attribute.Int("item.id", itemID))
+	item, err := t.ItemRepository.LoadItem(ctx, itemID, viewerID)
+	endSpan(span, 1, err)
+	return item, err
+}
+
+func (t *tracedItemRepository) SearchItems(ctx context.Context, keyword string, viewerID int) ([]*Item, error) {
+	ctx, span := t.start(ctx, "SearchItems", "SELECT")
+	items, err := t.ItemRepository.SearchItems(ctx, keyword, viewerID)
+	endSpan(span, len(items), err)
+	return items, err
+}
+
+func (t *tracedItemRepository) Update(ctx context.Context, item *Item) error {
+	ctx, span := t.start(ctx, "Update", "UPDATE", attribute.Int("item.id", item.ID))
+	err := t.ItemRepository.Update(ctx, item)
+	endSpan(span, 1, err)
+	return err
+}
+
+func (t *tracedItemRepository) Publish(ctx context.Context, itemID, sellerID int, publishAt time.Time) error {
+	ctx, span := t.start(ctx, "Publish", "UPDATE", attribute.Int("item.id", itemID))
+	err := t.ItemRepository.Publish(ctx, itemID, sellerID, publishAt)
+	endSpan(span, 1, err)
+	return err
+}
+
+func (t *tracedItemRepository) PublishDueItems(ctx context.Context, now time.Time) ([]int, error) {
+	ctx, span := t.start(ctx, "PublishDueItems", "UPDATE")
+	ids, err := t.ItemRepository.PublishDueItems(ctx, now)
+	endSpan(span, len(ids), err)
+	return ids, err
+}
+
+func (t *tracedItemRepository) InsertItems(ctx context.Context, items []*Item) error {
+	ctx, span := t.start(ctx, "InsertItems", "INSERT")
+	err := t.ItemRepository.InsertItems(ctx, items)
+	endSpan(span, len(items), err)
+	return err
+}
+
+func (t *tracedItemRepository) ForEachItem(ctx context.Context, viewerID int, fn func(*Item) error) error {
+	ctx, span := t.start(ctx, "ForEachItem", "SELECT")
+	rows := 0
+	err := t.ItemRepository.ForEachItem(ctx, viewerID, func(item *Item) error {
+		rows++
+		return fn(item)
+	})
+	endSpan(span, rows, err)
+	return err
+}
+
+func (t *tracedItemRepository) LoadImageNames(ctx context.Context) ([]string, error) {
+	ctx, span := t.start(ctx, "LoadImageNames", "SELECT")
+	names, err := t.ItemRepository.LoadImageNames(ctx)
+	endSpan(span, len(names), err)
+	return names, err
+}
+
+func (t *tracedItemRepository) LoadCategories(ctx context.Context) ([]*Category, error) {
+	ctx, span := t.start(ctx, "LoadCategories", "SELECT")
+	categories, err := t.ItemRepository.LoadCategories(ctx)
+	endSpan(span, len(categories), err)
+	return categories, err
+}
+
+func (t *tracedItemRepository) LoadItemsInCategories(ctx context.Context, categories []string, viewerID int) ([]*Item, error) {
+	ctx, span := t.start(ctx, "LoadItemsInCategories", "SELECT", attribute.StringSlice("item.categories", categories))
+	items, err := t.ItemRepository.LoadItemsInCategories(ctx, categories, viewerID)
+	endSpan(span, len(items), err)
+	return items, err
+}
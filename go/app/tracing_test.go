@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestTracerProvider returns a tracer provider recording the spans into the returned exporter.
+func newTestTracerProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return provider, exporter
+}
+
+// spanAttribute returns the value of the attribute of the span, or an invalid value if it is missing.
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracedItemRepository(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		items []*Item
+		err   error
+
+		wantRows   int64
+		wantStatus codes.Code
+	}{
+		"ok": {
+			items:      []*Item{{ID: 1}, {ID: 2}},
+			wantRows:   2,
+			wantStatus: codes.Unset,
+		},
+		"error": {
+			err:        errors.New("database is locked"),
+			wantStatus: codes.Error,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			mockIR.EXPECT().LoadItems(gomock.Any(), 0).Return(tt.items, tt.err)
+			provider, exporter := newTestTracerProvider(t)
+
+			repo := newTracedItemRepository(mockIR, provider)
+			if _, err := repo.LoadItems(context.Background(), 0); !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+			if span.Name != "ItemRepository.LoadItems" {
+				t.Errorf("unexpected span name %q", span.Name)
+			}
+			if got := spanAttribute(span, "db.operation.name").AsString(); got != "SELECT" {
+				t.Errorf("expected operation SELECT, got %q", got)
+			}
+			if got := spanAttribute(span, "db.response.returned_rows").AsInt64(); got != tt.wantRows {
+				t.Errorf("expected %d rows, got %d", tt.wantRows, got)
+			}
+			if span.Status.Code != tt.wantStatus {
+				t.Errorf("expected status %v, got %v", tt.wantStatus, span.Status.Code)
+			}
+		})
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	t.Parallel()
+
+	provider, exporter := newTestTracerProvider(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{item_id}", func(w http.ResponseWriter, r *http.Request) {})
+	h := tracingMiddleware(mux, mux, otelhttp.WithTracerProvider(provider))
+
+	for _, target := range []string{"/items/1", "/unknown"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, want := range []string{"GET /items/{item_id}", "GET"} {
+		if spans[i].Name != want {
+			t.Errorf("expected span name %q, got %q", want, spans[i].Name)
+		}
+	}
+}
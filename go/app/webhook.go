@@ -0,0 +1,197 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookSignatureHeader carries the HMAC-SHA256 of the request body keyed by the webhook secret,
+	// in the form "sha256=<hex>".
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookBackoff     = time.Second
+	webhookTimeout            = 10 * time.Second
+)
+
+// webhookEventTypes is the list of event types a webhook can subscribe to.
+// item.sold is accepted ahead of the purchase flow, which will publish it.
+var webhookEventTypes = []string{eventItemCreated, eventItemUpdated, eventItemSold}
+
+// WebhookPayload is the JSON body sent to webhooks.
+type WebhookPayload struct {
+	// ID is the ID of the event, shared by the retries of a delivery.
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	ItemID    int       `json:"item_id"`
+	Item      *Item     `json:"item"`
+}
+
+// WebhookDispatcher delivers item events to the subscribed webhooks.
+type WebhookDispatcher struct {
+	repo   WebhookRepository
+	client *http.Client
+	// maxAttempts is the number of attempts for a delivery before giving up.
+	maxAttempts int
+	// backoff is the wait before the first retry. It doubles for each following retry.
+	backoff time.Duration
+}
+
+// NewWebhookDispatcher creates a new WebhookDispatcher.
+func NewWebhookDispatcher(repo WebhookRepository) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:        repo,
+		client:      newPublicHTTPClient(webhookTimeout),
+		maxAttempts: defaultWebhookMaxAttempts,
+		backoff:     defaultWebhookBackoff,
+	}
+}
+
+// Run dispatches the events published on the bus until ctx is done.
+func (d *WebhookDispatcher) Run(ctx context.Context, bus *EventBus) {
+	_, events, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.Dispatch(ctx, ev)
+			}()
+		}
+	}
+}
+
+// Dispatch delivers an event to every webhook subscribing to it and waits for the deliveries to finish.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, ev ItemEvent) {
+	hooks, err := d.repo.LoadWebhooksForEvent(ctx, ev.Type)
+	if err != nil {
+		slog.Error("failed to load webhooks", "error", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		ID:        ev.ID,
+		Type:      ev.Type,
+		CreatedAt: time.Now().UTC(),
+		ItemID:    ev.ItemID,
+		Item:      ev.Item,
+	})
+	if err != nil {
+		slog.Error("failed to encode webhook payload", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, hook := range hooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, hook, ev, body)
+		}()
+	}
+	wg.Wait()
+}
+
+// deliver sends the payload to a webhook, retrying with exponential backoff until it succeeds
+// or the attempts run out. Every attempt is recorded in the delivery log.
+func (d *WebhookDispatcher) deliver(ctx context.Context, hook *Webhook, ev ItemEvent, body []byte) {
+	wait := d.backoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		statusCode, err := d.send(ctx, hook, ev, body)
+		delivery := &WebhookDelivery{
+			WebhookID:  hook.ID,
+			EventType:  ev.Type,
+			Payload:    string(body),
+			Attempt:    attempt,
+			StatusCode: statusCode,
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if err := d.repo.InsertDelivery(ctx, delivery); err != nil {
+			slog.Error("failed to record webhook delivery", "error", err)
+		}
+		if err == nil {
+			return
+		}
+
+		slog.Warn("failed to deliver webhook", "webhook_id", hook.ID, "attempt", attempt, "error", err)
+		if attempt == d.maxAttempts {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// send makes a single delivery attempt and returns the response status code.
+func (d *WebhookDispatcher) send(ctx context.Context, hook *Webhook, ev ItemEvent, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, ev.Type)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(ev.ID, 10))
+	req.Header.Set(webhookSignatureHeader, signWebhookPayload(hook.Secret, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// signWebhookPayload returns the value of the signature header for the body.
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validWebhookEvents reports whether every event is a known event type.
+func validWebhookEvents(events []string) bool {
+	if len(events) == 0 {
+		return false
+	}
+	for _, e := range events {
+		if !slices.Contains(webhookEventTypes, e) {
+			return false
+		}
+	}
+	return true
+}
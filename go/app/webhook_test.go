@@ -0,0 +1,180 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"mercari-build-training/app/webhooktest"
+)
+
+func TestWebhookDispatcherDispatch(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		failures int
+		wants    int // number of attempts
+	}{
+		"ok: delivered at first attempt": {failures: 0, wants: 1},
+		"ok: delivered after retries":    {failures: 2, wants: 3},
+		"ng: gave up after max attempts": {failures: 10, wants: 3},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			receiver := webhooktest.NewReceiver("secret")
+			t.Cleanup(receiver.Close)
+			receiver.FailNext(tt.failures)
+
+			ctrl := gomock.NewController(t)
+			mockWR := NewMockWebhookRepository(ctrl)
+			mockWR.EXPECT().LoadWebhooksForEvent(gomock.Any(), eventItemCreated).
+				Return([]*Webhook{{ID: 1, URL: receiver.URL, Secret: "secret"}}, nil)
+			mockWR.EXPECT().InsertDelivery(gomock.Any(), gomock.Any()).Return(nil).Times(tt.wants)
+
+			d := NewWebhookDispatcher(mockWR)
+			// the receiver listens on the loopback, which the client of the dispatcher refuses
+			d.client = &http.Client{Timeout: webhookTimeout}
+			d.maxAttempts = 3
+			d.backoff = time.Millisecond
+			d.Dispatch(t.Context(), ItemEvent{ID: 7, Type: eventItemCreated, ItemID: 1, Item: &Item{ID: 1, Name: "jacket"}})
+
+			deliveries := receiver.Deliveries()
+			if len(deliveries) != tt.wants {
+				t.Fatalf("expected %d deliveries, got %d", tt.wants, len(deliveries))
+			}
+			for _, d := range deliveries {
+				if !d.SignatureValid {
+					t.Errorf("invalid signature")
+				}
+				if d.Event != eventItemCreated {
+					t.Errorf("unexpected event: %s", d.Event)
+				}
+				var payload WebhookPayload
+				if err := json.Unmarshal(d.Body, &payload); err != nil {
+					t.Fatalf("failed to decode payload: %v", err)
+				}
+				if payload.ID != 7 || payload.Item.Name != "jacket" {
+					t.Errorf("unexpected payload: %+v", payload)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookDispatcherRefusesInternalHosts(t *testing.T) {
+	t.Parallel()
+
+	receiver := webhooktest.NewReceiver("secret")
+	t.Cleanup(receiver.Close)
+
+	ctrl := gomock.NewController(t)
+	mockWR := NewMockWebhookRepository(ctrl)
+	mockWR.EXPECT().LoadWebhooksForEvent(gomock.Any(), eventItemCreated).
+		Return([]*Webhook{{ID: 1, URL: receiver.URL, Secret: "secret"}}, nil)
+	mockWR.EXPECT().InsertDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(_ any, delivery *WebhookDelivery) error {
+		if !strings.Contains(delivery.Error, errInternalAddress.Error()) {
+			t.Errorf("expected the delivery to be refused, got %q", delivery.Error)
+		}
+		return nil
+	})
+
+	d := NewWebhookDispatcher(mockWR)
+	d.maxAttempts = 1
+	d.Dispatch(t.Context(), ItemEvent{ID: 1, Type: eventItemCreated, ItemID: 1, Item: &Item{ID: 1}})
+	if n := len(receiver.Deliveries()); n != 0 {
+		t.Errorf("expected no delivery to the loopback, got %d", n)
+	}
+}
+
+func TestParseAddWebhookRequest(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		url    string
+		events string
+
+		wantErr error
+	}{
+		"ok: public address": {url: "https://203.0.113.10/hooks", events: eventItemCreated},
+		"ng: loopback":       {url: "http://127.0.0.1:8080/hooks", events: eventItemCreated, wantErr: errInternalAddress},
+		"ng: IPv6 loopback":  {url: "http://[::1]/hooks", events: eventItemCreated, wantErr: errInternalAddress},
+		"ng: localhost":      {url: "http://localhost/hooks", events: eventItemCreated, wantErr: errInternalAddress},
+		"ng: private":        {url: "http://10.0.0.5/hooks", events: eventItemCreated, wantErr: errInternalAddress},
+		"ng: link-local":     {url: "http://169.254.169.254/latest/meta-data", events: eventItemCreated, wantErr: errInternalAddress},
+		"ok: sold event":     {url: "https://203.0.113.10/hooks", events: eventItemSold},
+		"ng: unknown event":  {url: "https://203.0.113.10/hooks", events: "item.deleted"},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			form := url.Values{"url": {tt.url}, "events": {tt.events}}
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			_, err := parseAddWebhookRequest(req)
+			wantErr := tt.wantErr != nil || strings.HasPrefix(name, "ng:")
+			if (err != nil) != wantErr {
+				t.Fatalf("expected error %t, got %v", wantErr, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWebhookRoutesAdminOnly(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockUR := NewMockUserRepository(ctrl)
+	mockUR.EXPECT().LoadUsers(gomock.Any(), []int{2}).Return([]*User{{ID: 2, Name: "seller"}}, nil).Times(4)
+	h := &Handlers{userRepo: mockUR, webhookRepo: NewMockWebhookRepository(ctrl), adminToken: "secret"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks", h.AddWebhook)
+	mux.HandleFunc("GET /webhooks", h.GetWebhooks)
+	mux.HandleFunc("DELETE /webhooks/{webhook_id}", h.DeleteWebhook)
+	mux.HandleFunc("GET /webhooks/{webhook_id}/deliveries", h.GetWebhookDeliveries)
+
+	for _, route := range []struct{ method, target string }{
+		{http.MethodPost, "/webhooks"},
+		{http.MethodGet, "/webhooks"},
+		{http.MethodDelete, "/webhooks/1"},
+		{http.MethodGet, "/webhooks/1/deliveries"},
+	} {
+		// a request without the admin token, or by a user who is not an admin, is rejected
+		for _, tt := range []struct {
+			userID, token string
+			wantCode      int
+		}{
+			{userID: "", token: "", wantCode: http.StatusForbidden},
+			{userID: "1", token: "wrong", wantCode: http.StatusForbidden},
+			{userID: "", token: "secret", wantCode: http.StatusBadRequest},
+			{userID: "2", token: "secret", wantCode: http.StatusForbidden},
+		} {
+			req := httptest.NewRequest(route.method, route.target, nil)
+			if tt.userID != "" {
+				req.Header.Set(userIDHeader, tt.userID)
+			}
+			req.Header.Set(adminTokenHeader, tt.token)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != tt.wantCode {
+				t.Errorf("%s %s by %q with token %q: expected status %d, got %d", route.method, route.target, tt.userID, tt.token, tt.wantCode, rr.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package webhooktest provides a webhook receiver for testing the deliveries of webhooks.
+package webhooktest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Delivery is a request received by a Receiver.
+type Delivery struct {
+	Event string
+	Body  []byte
+	// SignatureValid reports whether the X-Webhook-Signature header matches the body.
+	SignatureValid bool
+}
+
+// Receiver is an HTTP server recording the webhook deliveries it receives.
+type Receiver struct {
+	*httptest.Server
+
+	secret string
+
+	mu         sync.Mutex
+	deliveries []Delivery
+	failures   int
+}
+
+// NewReceiver starts a Receiver verifying the signatures with the secret.
+// The caller should call Close when finished, to shut it down.
+func NewReceiver(secret string) *Receiver {
+	r := &Receiver{secret: secret}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.handle))
+	return r
+}
+
+// FailNext makes the receiver respond to the next n deliveries with 500 Internal Server Error.
+func (r *Receiver) FailNext(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = n
+}
+
+// Deliveries returns the deliveries received so far, including the failed ones.
+func (r *Receiver) Deliveries() []Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Delivery(nil), r.deliveries...)
+}
+
+func (r *Receiver) handle(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(r.secret))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, Delivery{
+		Event:          req.Header.Get("X-Webhook-Event"),
+		Body:           body,
+		SignatureValid: hmac.Equal([]byte(want), []byte(req.Header.Get("X-Webhook-Signature"))),
+	})
+	if r.failures > 0 {
+		r.failures--
+		http.Error(w, "failed on purpose", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -13,8 +13,11 @@ const (
 func main() {
 	// This is the entry point of the application.
 	// You don't need to modify this function.
-	os.Exit(app.Server{
+	server := app.Server{
 		Port:         port,
 		ImageDirPath: imageDirPath,
-	}.Run())
+	}
+
+	// `api [flags] [command]` runs a subcommand, which starts the server by default. See `api -h`.
+	os.Exit(server.Main(os.Args[1:]))
 }
@@ -1,4 +1,6 @@
 const SERVER_URL = import.meta.env.VITE_BACKEND_URL || 'http://127.0.0.1:9000';
+// USER_ID is sent as the X-User-ID header, since the server lists the items as drafts of their seller.
+const USER_ID = import.meta.env.VITE_USER_ID || '1';
 
 export interface Item {
   id: number;
@@ -37,7 +39,21 @@ export const postItem = async (input: CreateItemInput): Promise<Response> => {
   const response = await fetch(`${SERVER_URL}/items`, {
     method: 'POST',
     mode: 'cors',
+    headers: {
+      'X-User-ID': USER_ID,
+    },
     body: data,
   });
-  return response;
+  if (!response.ok) {
+    return response;
+  }
+  // publish the draft right away, so that the item is listed as before
+  const item: Item = await response.json();
+  return fetch(`${SERVER_URL}/items/${item.id}/publish`, {
+    method: 'POST',
+    mode: 'cors',
+    headers: {
+      'X-User-ID': USER_ID,
+    },
+  });
 };